@@ -1,17 +1,26 @@
 package main
 
 import (
+	"flag"
 	"log"
 
 	"github.com/MrMamka/combchats/internal/bot"
 )
 
 func main() {
+	debug := flag.Bool("debug", false, "log raw requests and updates of Telegram API, texts of messages included")
+	flag.Parse()
+
 	tgBot := bot.NewTelegramBot()
 	if err := tgBot.SetTokenFromEnv("BOT_TOKEN"); err != nil {
 		log.Fatalf("Error while setting token: %v", err)
 	}
-	if err := tgBot.Start(true); err != nil {
+	tgBot.SetMatrixFromEnv("MATRIX_HOMESERVER", "MATRIX_TOKEN")
+	tgBot.StartWebhooksFromEnv("WEBHOOK_ADDR")
+	if err := tgBot.SetStoreFromEnv("STATE_FILE", "STATE_KEY"); err != nil {
+		log.Fatalf("Error while opening state store: %v", err)
+	}
+	if err := tgBot.Start(*debug); err != nil {
 		log.Fatalf("Error during bot working: %v", err)
 	}
 }
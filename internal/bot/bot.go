@@ -3,11 +3,16 @@ package bot
 import (
 	"errors"
 	"fmt"
+	"html"
 	"log"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/i18n"
+	"github.com/MrMamka/combchats/internal/store"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/joho/godotenv"
 )
@@ -16,7 +21,6 @@ var ErrNotFoundEnv = errors.New("env not found")
 
 type Stage int
 
-// TODO: добавить настройку для вывода площадки и стрима в сообщении при объединении
 const (
 	NotWorkingStage Stage = iota
 	ChooseModeStage
@@ -24,82 +28,122 @@ const (
 	PendingChatsStage
 	WorkingCombiningStage
 
-	PendingTwoChatsStage
-	PendingDirectionStage
+	PendingForwardingChatsStage
+	PendingRoutesStage
 	PendingTokensStage
 	WorkingForwardingStage
 )
 
-var helpMessages = map[Stage]string{
-	NotWorkingStage: "Бот умеет объединять чаты стримов. В данный момент поддерживаются площадки: Vk Play Live и Twitch",
-	ChooseModeStage: "В режиме объединения сообщения будут появляться в этом телеграм чате. " +
-		"В режим пересылки сообщения будут отправляться из одного чата стрима в другой",
-
-	PendingChatsStage: "Правильное написание ника стримера можно узнать в URL его стрима." +
-		"Название площадок должно быть ровно такое, как было написано выше (в данный момент оно чувствительно к регистру)",
-	WorkingCombiningStage: "Чтобы остановить поток сообщений напишите /stop или /restart",
-
-	PendingTwoChatsStage: "Правильное написание ника стримера можно узнать в URL его стрима." +
-		"Название площадок должно быть ровно такое, как было написано выше (в данный момент оно чувствительно к регистру)",
-	PendingDirectionStage: "/first пересылает в первый чат из второго, /second - во второй из первого, /both - /first и /second одновременно",
-	PendingTokensStage: `Ник можно узнать в URL, зайдя на свой канал.
-	Vk токен можно узнать после входа в аккаунт vk play live в консоли разработчика, в Cookie Header'е одного из запросов. Он идёт после accessToken. Пример токена:
-	7a41109f60fbb5aa16dcb4c4d3ea4a3ffac4af1d22aa8998b8a0209d0231faba (этот токен не настоящий)
-	Twitch токен можно узнать на специальных сайтах. Например twitchtokengenerator.com. Пример токена:
-	oauth:uy1tkpc8fer0xbh122ewrmq1cked2b (этот токен не настоящий)
-	В данный момент нет валидации токенов, поэтому неправильность токенов можно узнать только по тому, что бот не будет работать. В такой ситуации можно написать /restart`,
-	WorkingForwardingStage: "Чтобы остановить пересылку сообщений напишите /stop или /restart",
+// helpMessages are catalog keys of help parts shown by /help in each stage.
+var helpMessages = map[Stage][]string{
+	NotWorkingStage:             {"help.not_working"},
+	ChooseModeStage:             {"help.choose_mode"},
+	PendingChatsStage:           {"help.pending_chats", "help.telegram_group"},
+	WorkingCombiningStage:       {"help.combining", "help.template"},
+	PendingForwardingChatsStage: {"help.pending_chats", "help.telegram_group"},
+	PendingRoutesStage:          {"help.pending_routes"},
+	PendingTokensStage:          {"help.pending_tokens"},
+	WorkingForwardingStage:      {"help.forwarding", "help.template"},
+}
+
+// helpText returns help of the stage followed by help of sessions.
+func helpText(stage Stage, lang i18n.Language) string {
+	parts := make([]string, len(helpMessages[stage]))
+	for i, key := range helpMessages[stage] {
+		parts[i] = lang.T(key)
+	}
+	return strings.Join(parts, " ") + "\n\n" + lang.T("help.sessions")
 }
 
 var Platforms = map[string]chat.ChannelType{
-	"Twitch": chat.TwitchChannelType,
-	"Vk":     chat.VkChannelType,
+	"Twitch":   chat.TwitchChannelType,
+	"Vk":       chat.VkChannelType,
+	"Telegram": chat.TelegramChannelType,
+	"IRC":      chat.IRCChannelType,
+	"Matrix":   chat.MatrixChannelType,
+	"Webhook":  chat.WebhookChannelType,
 }
 
 var PlatformsTypes = map[chat.ChannelType]string{
-	chat.TwitchChannelType: "Twitch",
-	chat.VkChannelType:     "Vk",
+	chat.TwitchChannelType:   "Twitch",
+	chat.VkChannelType:       "Vk",
+	chat.TelegramChannelType: "Telegram",
+	chat.IRCChannelType:      "IRC",
+	chat.MatrixChannelType:   "Matrix",
+	chat.WebhookChannelType:  "Webhook",
 }
 
 func AvailbalePlatforms() string {
-	platforms := make([]string, 0, len(Platforms))
-	for platform := range Platforms {
-		platforms = append(platforms, platform)
+	platforms := make([]string, 0, len(platformOrder))
+	for _, platform := range platformOrder {
+		platforms = append(platforms, PlatformsTypes[platform])
 	}
 	return strings.Join(platforms, "/")
 }
 
-type Forwarding int
-
-const (
-	FirstForwarding Forwarding = iota
-	SecondForwarding
-	BothForwarding
-)
-
 type recieverInfo struct {
 	token      string
 	senderName string
 }
 
 type status struct {
-	stage     Stage
 	channels  []chat.Channel
-	forwardTo Forwarding
+	routes    []chat.Route
 	receivers []recieverInfo
-	stop      chan struct{}
+	started   time.Time
+	filter    *chat.Filter
+	emotes    *chat.EmoteTable
+
+	// pendingPlatform is the platform chosen by button for the next entered channel.
+	pendingPlatform *chat.ChannelType
+	// wizardMessageID is the message with keyboard of the current stage, zero if there is none.
+	wizardMessageID int
+
+	// dialogue is the telegram chat of the session.
+	dialogue *dialogue
+	// savedRouter are settings of the router restored from store, they are applied when forwarding is resumed.
+	savedRouter *chat.RouterSettings
+
+	mu sync.Mutex
+	// stage and stop are changed by handlers of the session and read by commands of the chat, see currentStage.
+	stage Stage
+	stop  chan struct{}
+	// stopped is set when stop is closed. The channel is kept, so chats which start after /stop see it closed.
+	stopped  bool
+	name     string
+	template chat.Template
+	// links tells whether authors of combined messages link to their channels.
+	links bool
+	// router is set when forwarding is started, see currentRouter.
+	router *chat.Router
+	// combined is set when combined chat is started, see currentCombined.
+	combined *chat.CombinedChat
 }
 
 type TelegramBot struct {
-	Token           string
-	bot             *tgbotapi.BotAPI
-	dialoguesStatus map[int64]*status
-	stageHandlers   map[Stage]func(*tgbotapi.Message, *status) error
+	Token         string
+	bot           *tgbotapi.BotAPI
+	dialogues     map[int64]*dialogue
+	stageHandlers map[Stage]func(*tgbotapi.Message, *status) error
+	// callbackHandlers handle presses of keyboard buttons in stages which have them.
+	callbackHandlers map[Stage]func(*tgbotapi.CallbackQuery, *status) error
+	// sessionCommands are commands available in every stage of a started session.
+	sessionCommands map[string]func(*tgbotapi.Message, *status) error
+	// dialogueCommands manage sessions of the chat, they are available in every stage.
+	dialogueCommands map[string]func(*tgbotapi.Message, *dialogue) error
+
+	// store keeps sessions between restarts, it is nil if sessions are not saved. Tokens in it are encrypted by cipher.
+	store  store.Store
+	cipher *store.Cipher
+	saveMu sync.Mutex
+	// secrets hides tokens in logs.
+	secrets *redactor
 }
 
 func NewTelegramBot() *TelegramBot {
 	tb := new(TelegramBot)
-	tb.dialoguesStatus = make(map[int64]*status)
+	tb.dialogues = make(map[int64]*dialogue)
+	tb.secrets = newRedactor()
 	tb.stageHandlers = map[Stage]func(*tgbotapi.Message, *status) error{
 		NotWorkingStage: tb.notWorkingHandler,
 		ChooseModeStage: tb.chooseModeHandler,
@@ -107,217 +151,195 @@ func NewTelegramBot() *TelegramBot {
 		PendingChatsStage:     tb.pendingChatsHandler,
 		WorkingCombiningStage: tb.workingCombiningHandler,
 
-		PendingTwoChatsStage:   tb.pendingTwoChatsHandler,
-		PendingDirectionStage:  tb.pendingDirectionHandler,
-		PendingTokensStage:     tb.pendingTokensHandler,
-		WorkingForwardingStage: tb.workingForwardingHandler,
+		PendingForwardingChatsStage: tb.pendingForwardingChatsHandler,
+		PendingRoutesStage:          tb.pendingRoutesHandler,
+		PendingTokensStage:          tb.pendingTokensHandler,
+		WorkingForwardingStage:      tb.workingForwardingHandler,
+	}
+	tb.callbackHandlers = map[Stage]func(*tgbotapi.CallbackQuery, *status) error{
+		ChooseModeStage:             tb.chooseModeCallback,
+		PendingChatsStage:           tb.chatsCallback,
+		PendingForwardingChatsStage: tb.chatsCallback,
+		PendingRoutesStage:          tb.routesCallback,
+	}
+	tb.sessionCommands = map[string]func(*tgbotapi.Message, *status) error{
+		"filter": tb.filterHandler,
+		"emotes": tb.emotesHandler,
+	}
+	tb.dialogueCommands = map[string]func(*tgbotapi.Message, *dialogue) error{
+		"sessions": tb.sessionsHandler,
+		"new":      tb.newSessionHandler,
+		"switch":   tb.switchSessionHandler,
+		"rename":   tb.renameSessionHandler,
+		"close":    tb.closeSessionHandler,
+
+		"credentials": tb.credentialsHandler,
+		"revoke":      tb.revokeHandler,
+
+		"language": tb.languageHandler,
 	}
 
 	return tb
 }
 
 func (tb *TelegramBot) notWorkingHandler(msgReq *tgbotapi.Message, stat *status) error {
-	stat.stage = ChooseModeStage
+	stat.resetStop()
+	stat.setStage(ChooseModeStage)
 	stat.channels = []chat.Channel{}
+	stat.routes = []chat.Route{}
 	stat.receivers = []recieverInfo{}
-	stat.stop = make(chan struct{})
+	stat.filter = chat.NewFilter()
+	stat.emotes = chat.DefaultEmotes()
+	stat.setTemplate(chat.DefaultTemplate)
+	stat.pendingPlatform = nil
 
-	return tb.sendMsg(msgReq.Chat.ID,
-		"Выберите режим, в котором хотите использовать бота: персылка сообщений (/forwarding) или объединение чатов (/combining)")
+	return tb.sendWizard(msgReq.Chat.ID, stat, stat.t("mode.choose"), modeKeyboard(stat.language()))
 }
 
 func (tb *TelegramBot) chooseModeHandler(msgReq *tgbotapi.Message, stat *status) error {
-	switch msgReq.Text {
-	case "/combining":
-		stat.stage = PendingChatsStage
-		return tb.sendMsg(msgReq.Chat.ID, fmt.Sprintf(
-			"Вводите названия платформ (%s) и ники стримеров, чаты которых хотите видеть, "+
-				"отдельными сообщениями в формате \"*плафторма* *ник*\" (без кавычек и звёздочек). "+
-				"Конец ввода подтвердите командой /done", AvailbalePlatforms()))
-	case "/forwarding":
-		stat.stage = PendingTwoChatsStage
-		return tb.sendMsg(msgReq.Chat.ID, fmt.Sprintf(
-			"Вводите названия платформ (%s) и ники стримеров, чаты которых хотите видеть, "+
-				"отдельными сообщениями в формате \"*плафторма* *ник*\" (без кавычек и звёздочек).", AvailbalePlatforms()))
+	return tb.chooseMode(msgReq.Chat.ID, stat, strings.TrimPrefix(msgReq.Text, "/"))
+}
+
+func (tb *TelegramBot) chooseMode(chatID int64, stat *status, mode string) error {
+	switch mode {
+	case "combining":
+		stat.setStage(PendingChatsStage)
+		stat.setTemplate(chat.DefaultCombinedTemplate)
+	case "forwarding":
+		stat.setStage(PendingForwardingChatsStage)
 	default:
-		return tb.sendMsg(msgReq.Chat.ID, "Неподдерживаемый режим. Выберите /forwarding или /combining")
+		return tb.sendMsg(chatID, stat.t("mode.unsupported"))
 	}
+	return tb.sendWizard(chatID, stat, chatsWizardText(stat), chatsKeyboard(stat))
 }
 
 func (tb *TelegramBot) pendingChatsHandler(msgReq *tgbotapi.Message, stat *status) error {
 	if msgReq.Text == "/done" {
-		stat.stage = WorkingCombiningStage
-		return tb.startChats(msgReq.Chat.ID, stat) // TODO: validate size of channels?
-	}
-
-	input := strings.Fields(msgReq.Text)
-	if len(input) != 2 {
-		return tb.sendMsg(msgReq.Chat.ID, "Неверный формат ввода. Ожидалось \"*плафторма* *ник*\" или /done")
-	}
-
-	platform, ok := Platforms[input[0]] // TODO: убрать чувствительность в регистру (добавить возможность написать по-русскими?)
-	if !ok {
-		tb.sendMsg(msgReq.Chat.ID, fmt.Sprintf("Неизвестная платформа. Доступные: %s", AvailbalePlatforms()))
-		return nil
+		return tb.finishCombiningChats(msgReq.Chat.ID, stat)
 	}
-
-	stat.channels = append(stat.channels, chat.Channel{Type: platform, Name: input[1]}) // TODO: validate name
-
-	return tb.sendMsg(msgReq.Chat.ID, "Записано")
+	return tb.addChannel(msgReq, stat)
 }
 
-func (tb *TelegramBot) workingCombiningHandler(msgReq *tgbotapi.Message, stat *status) error {
-	switch msgReq.Text {
-	case "/stop":
-		close(stat.stop)
-		stat.stage = NotWorkingStage
-		return tb.sendMsg(msgReq.Chat.ID, "Чат остановлен.")
-	default:
-		return tb.sendMsg(msgReq.Chat.ID, "Если хотите остановить чат - напишите /stop")
+func (tb *TelegramBot) finishCombiningChats(chatID int64, stat *status) error {
+	if len(stat.channels) == 0 {
+		return tb.sendMsg(chatID, stat.t("chats.none"))
 	}
+	tb.clearWizard(chatID, stat)
+	stop := stat.startSession(WorkingCombiningStage)
+	return tb.startChats(chatID, stat, stop)
 }
 
-func (tb *TelegramBot) pendingTwoChatsHandler(msgReq *tgbotapi.Message, stat *status) error {
-	// TODO: вынести код, так как в pendingChatsHandler копипаст
-	input := strings.Fields(msgReq.Text)
-	if len(input) != 2 {
-		return tb.sendMsg(msgReq.Chat.ID, "Неверный формат ввода. Ожидалось \"*плафторма* *ник*\" или /done")
+// addChannel adds channels entered by user. If platform was chosen by button, the message can be only a name.
+func (tb *TelegramBot) addChannel(msgReq *tgbotapi.Message, stat *status) error {
+	channels, err := parseChannelsInput(msgReq.Text, stat.language())
+	if name := strings.TrimSpace(msgReq.Text); err != nil && stat.pendingPlatform != nil && len(strings.Fields(name)) == 1 {
+		var channel chat.Channel
+		channel, err = newChannel(*stat.pendingPlatform, name, stat.language())
+		channels = []chat.Channel{channel}
 	}
-
-	platform, ok := Platforms[input[0]] // TODO: убрать чувствительность в регистру (добавить возможность написать по-русскими?)
-	if !ok {
-		return tb.sendMsg(msgReq.Chat.ID, fmt.Sprintf("Неизвестная платформа. Доступные: %s", AvailbalePlatforms()))
+	if err != nil {
+		return tb.sendMsg(msgReq.Chat.ID, err.Error())
 	}
+	tb.keepChannelSecrets(msgReq, channels, stat.language())
 
-	stat.channels = append(stat.channels, chat.Channel{Type: platform, Name: input[1]}) // TODO: validate name
-
-	if len(stat.channels) == 1 {
-		return tb.sendMsg(msgReq.Chat.ID, "Записал. Введите в том же формате ещё один чат, который хотите отслеживать")
-	} else if len(stat.channels) == 2 {
-		stat.stage = PendingDirectionStage
-		return tb.sendMsg(msgReq.Chat.ID, fmt.Sprintf(
-			`Записал. Теперь выбери, куда пересылать сообщения.
-		/first - пересылать сообщения из %s %s в %s %s (из второго чата в первый)
-		/second - пересылать сообщения из %s %s в %s %s (из первого чата во второй)
-		/both - пересылать сообщения друг в друга`,
-			PlatformsTypes[stat.channels[1].Type], stat.channels[1].Name, // TODO: убрать этот копипаст?
-			PlatformsTypes[stat.channels[0].Type], stat.channels[0].Name,
-			PlatformsTypes[stat.channels[0].Type], stat.channels[0].Name,
-			PlatformsTypes[stat.channels[1].Type], stat.channels[1].Name))
-	} else {
-		return tb.sendMsg(msgReq.Chat.ID, "Что-то сломалось. Попробуйте перезапустить меня или обратиться к @mrmamka")
-	}
-}
+	stat.pendingPlatform = nil
 
-func (tb *TelegramBot) pendingDirectionHandler(msgReq *tgbotapi.Message, stat *status) error {
-	var channel chat.Channel
-	switch msgReq.Text {
-	case "/first":
-		stat.forwardTo = FirstForwarding
-		channel = stat.channels[0]
-	case "/second":
-		stat.forwardTo = SecondForwarding
-		channel = stat.channels[1]
-	case "/both":
-		stat.forwardTo = BothForwarding
-		channel = stat.channels[0]
-	default:
-		return tb.sendMsg(msgReq.Chat.ID, "Неизвестная команда. Введите /first, /second или /both")
+	understood := make([]string, len(channels))
+	for i, channel := range channels {
+		if hasChannel(stat.channels, channel) {
+			understood[i] = "• " + stat.t("wizard.duplicate", channelTitle(channel))
+			continue
+		}
+		stat.channels = append(stat.channels, channel)
+		understood[i] = "• " + channelTitle(channel)
 	}
-	stat.stage = PendingTokensStage
-
-	return tb.sendMsg(msgReq.Chat.ID, fmt.Sprintf(
-		"Введите имя и %s токен от аккаунта с которого будут отправляться сообщения в %s. В формате \"*имя* *токен*\"", // TODO: не просить, если платформа - вк
-		PlatformsTypes[channel.Type], channel.Name))
+	return tb.sendWizard(msgReq.Chat.ID, stat,
+		stat.t("wizard.added", strings.Join(understood, "\n"), chatsWizardText(stat)), chatsKeyboard(stat))
 }
 
-// TODO: валидировать токен
-func (tb *TelegramBot) pendingTokensHandler(msgReq *tgbotapi.Message, stat *status) error { // TODO: добавить /help (и написать, что он есть) про токены
-	input := strings.Fields(msgReq.Text)
-	if len(input) != 2 {
-		return tb.sendMsg(msgReq.Chat.ID, "Неверный формат ввода. Ожидалось \"*имя* *токен*\"")
+// hasChannel reports whether channel is in channels. Secrets are not compared, one webhook path is one channel.
+func hasChannel(channels []chat.Channel, channel chat.Channel) bool {
+	for _, existing := range channels {
+		if existing.Type == channel.Type && existing.Name == channel.Name {
+			return true
+		}
 	}
+	return false
+}
 
-	stat.receivers = append(stat.receivers, recieverInfo{senderName: input[0], token: input[1]})
-
-	if stat.forwardTo == BothForwarding && len(stat.receivers) == 1 {
-		return tb.sendMsg(msgReq.Chat.ID, fmt.Sprintf(
-			"Записано. Теперь введите %s токен от аккаунта с которого будут отправляться сообщения в %s",
-			PlatformsTypes[stat.channels[1].Type], stat.channels[1].Name))
+// keepChannelSecrets hides secrets of entered channels in logs and removes the message with them from the chat.
+func (tb *TelegramBot) keepChannelSecrets(msgReq *tgbotapi.Message, channels []chat.Channel, lang i18n.Language) {
+	deleted := false
+	for _, channel := range channels {
+		if channel.Secret == "" {
+			continue
+		}
+		tb.secrets.Add(channel.Secret)
+		if !deleted {
+			tb.deleteCredentialsMessage(msgReq, lang)
+			deleted = true
+		}
 	}
+}
 
-	tb.sendMsg(msgReq.Chat.ID, "Записано")
-	stat.stage = WorkingForwardingStage
-	return tb.startForwarding(msgReq.Chat.ID, stat)
+func channelTitle(channel chat.Channel) string {
+	return fmt.Sprintf("%s %s", PlatformsTypes[channel.Type], channel.Name)
 }
 
-func (tb *TelegramBot) workingForwardingHandler(msgReq *tgbotapi.Message, stat *status) error {
+func (tb *TelegramBot) workingCombiningHandler(msgReq *tgbotapi.Message, stat *status) error {
 	switch msgReq.Text {
 	case "/stop":
-		close(stat.stop)
-		stat.stage = NotWorkingStage
-		return tb.sendMsg(msgReq.Chat.ID, "Пересылку остановлена")
+		stat.stopSession()
+		stat.setStage(NotWorkingStage)
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("combining.stopped"))
 	default:
-		return tb.sendMsg(msgReq.Chat.ID, "Если хотите остановить пересылку - напишите /stop")
-	}
-}
-
-func (tb *TelegramBot) startForwarding(chatID int64, stat *status) error {
-	_ = tb.sendMsg(chatID, "Запускаю...")
-
-	switch stat.forwardTo {
-	case FirstForwarding:
-		chat.Forward(stat.channels[1], chat.Reciever{
-			Channel:    stat.channels[0],
-			AuthToken:  stat.receivers[0].token,
-			SenderName: stat.receivers[0].senderName},
-			stat.stop)
-	case SecondForwarding:
-		chat.Forward(stat.channels[0], chat.Reciever{
-			Channel:    stat.channels[1],
-			AuthToken:  stat.receivers[0].token,
-			SenderName: stat.receivers[0].senderName},
-			stat.stop)
-	case BothForwarding:
-		sentMessage := make(map[string]struct{})
-
-		chat.Forward(stat.channels[0], chat.Reciever{
-			Channel:    stat.channels[1],
-			AuthToken:  stat.receivers[1].token,
-			SenderName: stat.receivers[1].senderName,
-			SentMsgs:   sentMessage},
-			stat.stop)
-
-		chat.Forward(stat.channels[1], chat.Reciever{
-			Channel:    stat.channels[0],
-			AuthToken:  stat.receivers[0].token,
-			SenderName: stat.receivers[0].senderName,
-			SentMsgs:   sentMessage},
-			stat.stop)
+		switch msgReq.Command() {
+		case "template":
+			return tb.combiningTemplateHandler(msgReq, stat)
+		case "add":
+			return tb.addChatsHandler(msgReq, stat)
+		case "remove":
+			return tb.removeChatsHandler(msgReq, stat)
+		case "list":
+			return tb.listChatsHandler(msgReq, stat)
+		case "status":
+			return tb.statusHandler(msgReq, stat)
+		case "links":
+			return tb.linksHandler(msgReq, stat)
+		}
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("combining.commands"))
 	}
-
-	_ = tb.sendMsg(chatID, "Готово!")
-
-	return nil
 }
 
-func (tb *TelegramBot) startChats(chatID int64, stat *status) error { //TODO: Добавить обработку ошибку (сейчас так: _, _)
-	_ = tb.sendMsg(chatID, "Запускаю...")
+// startChats starts combined chat of the session until stop is closed. If it can't be started, the session is stopped
+// and the chat is told why.
+func (tb *TelegramBot) startChats(chatID int64, stat *status, stop <-chan struct{}) error {
+	_ = tb.sendMsg(chatID, stat.t("start.starting"))
+	stat.setCombined(nil)
 
-	combChat, _ := chat.NewCombinedChat(stat.channels)
-	outputChan := combChat.Start(stat.stop)
+	combChat, err := chat.NewCombinedChat(stat.channels)
+	if err != nil {
+		stat.abortSession(stop)
+		_ = tb.sendMsg(chatID, stat.t("combining.start_failed", err))
+		return err
+	}
+	combChat.SetFilter(stat.filter)
+	outputChan := combChat.Start(stop)
+	stat.setCombined(combChat)
+	stat.started = time.Now()
 
-	_ = tb.sendMsg(chatID, "Готово!")
+	_ = tb.sendMsg(chatID, stat.t("start.done"))
 
 	go func() {
 		for {
 			select {
-			case <-stat.stop:
+			case <-stop:
 				return
 			case msg := <-outputChan:
-				textResp := chat.MessageToText(msg) // TODO: Вынести в отдельную функцию?
+				textResp := html.EscapeString(stat.sessionLabel()) +
+					stat.currentTemplate().In(stat.language()).FormatHTML(chat.SanitizeMessage(msg), stat.showLinks())
 
-				_ = tb.sendMsg(chatID, textResp)
+				_ = tb.sendHTML(chatID, textResp)
 			}
 		}
 	}()
@@ -325,31 +347,77 @@ func (tb *TelegramBot) startChats(chatID int64, stat *status) error { //TODO: Д
 	return nil
 }
 
-// TODO: выводить системные сообщения жирным.
+// sendMsg sends system message of the bot, it is bold to stand out from messages of chats.
 func (tb *TelegramBot) sendMsg(chatId int64, msgText string) error {
-	msgResp := tgbotapi.NewMessage(chatId, msgText)
-	_, err := tb.bot.Send(msgResp)
-	return err
+	return tb.sendHTML(chatId, systemHTML(msgText))
+}
+
+// sendHTML sends text in telegram HTML, long text is split into several messages.
+func (tb *TelegramBot) sendHTML(chatId int64, text string) error {
+	for _, part := range chat.SplitHTML(text, chat.TelegramMessageLimit) {
+		msgResp := tgbotapi.NewMessage(chatId, part)
+		msgResp.ParseMode = tgbotapi.ModeHTML
+		msgResp.DisableWebPagePreview = true
+		if _, err := tb.bot.Send(msgResp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func systemHTML(text string) string {
+	return "<b>" + html.EscapeString(text) + "</b>"
+}
+
+// logMessage logs incoming message. Messages with credentials are not logged at all.
+func (tb *TelegramBot) logMessage(msgReq *tgbotapi.Message) {
+	text := msgReq.Text
+	if d := tb.dialogues[msgReq.Chat.ID]; d != nil && d.current.currentStage() == PendingTokensStage && !msgReq.IsCommand() {
+		text = "<credentials hidden>"
+	}
+	log.Printf("[%s] %s", msgReq.From.UserName, text)
 }
 
 func (tb *TelegramBot) handleMsg(msgReq *tgbotapi.Message) {
-	if tb.dialoguesStatus[msgReq.Chat.ID] == nil {
-		tb.dialoguesStatus[msgReq.Chat.ID] = &status{stage: NotWorkingStage}
+	// Messages from bridged telegram groups are chat messages, not commands to the bot.
+	if chat.Telegram.Dispatch(msgReq) && !msgReq.IsCommand() {
+		return
+	}
+
+	d := tb.dialogues[msgReq.Chat.ID]
+	if d == nil {
+		d = newDialogue()
+		tb.dialogues[msgReq.Chat.ID] = d
 	}
+	if d.lang == "" && msgReq.From != nil {
+		d.setLanguage(i18n.FromTelegram(msgReq.From.LanguageCode))
+	}
+	// Session commands change which session gets the next messages, so they are handled in order.
+	if handler, ok := tb.dialogueCommands[msgReq.Command()]; ok {
+		handler(msgReq, d)
+		tb.saveDialogue(msgReq.Chat.ID, d)
+		return
+	}
+	stat := d.current
 
 	if msgReq.Text == "/restart" {
-		if tb.dialoguesStatus[msgReq.Chat.ID].stop != nil {
-			close(tb.dialoguesStatus[msgReq.Chat.ID].stop)
-		}
-		tb.dialoguesStatus[msgReq.Chat.ID].stage = NotWorkingStage
+		stat.stopSession()
+		stat.setStage(NotWorkingStage)
 	} else if msgReq.Text == "/help" {
-		stage := tb.dialoguesStatus[msgReq.Chat.ID].stage
-		tb.sendMsg(msgReq.Chat.ID, helpMessages[stage])
+		tb.sendMsg(msgReq.Chat.ID, helpText(stat.currentStage(), d.language()))
+		return
+	} else if handler, ok := tb.sessionCommands[msgReq.Command()]; ok && stat.currentStage() != NotWorkingStage {
+		go tb.handleAndSave(msgReq, stat, handler)
 		return
 	}
 
-	stage := tb.dialoguesStatus[msgReq.Chat.ID].stage
-	go tb.stageHandlers[stage](msgReq, tb.dialoguesStatus[msgReq.Chat.ID])
+	go tb.handleAndSave(msgReq, stat, tb.stageHandlers[stat.currentStage()])
+}
+
+// handleAndSave handles message in the session and saves changed state.
+func (tb *TelegramBot) handleAndSave(msgReq *tgbotapi.Message, stat *status, handler func(*tgbotapi.Message, *status) error) {
+	handler(msgReq, stat)
+	tb.saveDialogue(msgReq.Chat.ID, stat.dialogue)
 }
 
 func (tb *TelegramBot) Start(isDebug bool) error {
@@ -359,10 +427,16 @@ func (tb *TelegramBot) Start(isDebug bool) error {
 		return err
 	}
 
+	// Debug output of the API contains texts of messages, so tokens sent by users must be hidden there too.
+	log.SetOutput(tb.secrets.Writer(os.Stderr))
+	_ = tgbotapi.SetLogger(log.New(tb.secrets.Writer(os.Stderr), "", log.LstdFlags))
 	tb.bot.Debug = isDebug
+	chat.Telegram.SetAPI(tb.bot)
 
 	log.Printf("Authorized on account %s", tb.bot.Self.UserName)
 
+	tb.resumeSessions()
+
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 180
 
@@ -371,14 +445,43 @@ func (tb *TelegramBot) Start(isDebug bool) error {
 	for update := range updates {
 		if update.Message != nil {
 			msgReq := update.Message
-			log.Printf("[%s] %s", msgReq.From.UserName, msgReq.Text)
+			tb.logMessage(msgReq)
 
 			tb.handleMsg(msgReq)
 		}
+		if update.CallbackQuery != nil {
+			tb.handleCallback(update.CallbackQuery)
+		}
 	}
 	return nil
 }
 
+// StartWebhooksFromEnv starts server for webhook chats on address from env. Webhooks are disabled if env is empty.
+func (tb *TelegramBot) StartWebhooksFromEnv(env string) {
+	addr := os.Getenv(env)
+	if addr == "" {
+		return
+	}
+	// Server is started before sessions are resumed, so their webhook chats can be created.
+	listener, err := chat.Webhooks.Listen(addr)
+	if err != nil {
+		log.Printf("Unable to start webhook server: %v", err)
+		return
+	}
+	go func() {
+		log.Printf("Webhook server stopped: %v", chat.Webhooks.Serve(listener))
+	}()
+}
+
+// SetMatrixFromEnv sets the account used for reading matrix rooms. Matrix rooms can't be read without it.
+func (tb *TelegramBot) SetMatrixFromEnv(homeserverEnv, tokenEnv string) {
+	chat.Matrix = chat.MatrixConfig{
+		Homeserver:  os.Getenv(homeserverEnv),
+		AccessToken: os.Getenv(tokenEnv),
+	}
+	tb.secrets.Add(chat.Matrix.AccessToken)
+}
+
 func (tb *TelegramBot) SetTokenFromEnv(env string) error {
 	if err := godotenv.Load(); err != nil {
 		return err
@@ -387,5 +490,6 @@ func (tb *TelegramBot) SetTokenFromEnv(env string) error {
 	if tb.Token == "" {
 		return ErrNotFoundEnv
 	}
+	tb.secrets.Add(tb.Token)
 	return nil
 }
@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/i18n"
+)
+
+// platformAliases are lowercased names of platforms accepted in channel input.
+var platformAliases = map[string]chat.ChannelType{
+	"twitch":     chat.TwitchChannelType,
+	"tw":         chat.TwitchChannelType,
+	"твич":       chat.TwitchChannelType,
+	"твитч":      chat.TwitchChannelType,
+	"vk":         chat.VkChannelType,
+	"vkplay":     chat.VkChannelType,
+	"vkplaylive": chat.VkChannelType,
+	"вк":         chat.VkChannelType,
+	"вкплей":     chat.VkChannelType,
+	"telegram":   chat.TelegramChannelType,
+	"tg":         chat.TelegramChannelType,
+	"телеграм":   chat.TelegramChannelType,
+	"телеграмм":  chat.TelegramChannelType,
+	"тг":         chat.TelegramChannelType,
+	"irc":        chat.IRCChannelType,
+	"matrix":     chat.MatrixChannelType,
+	"матрикс":    chat.MatrixChannelType,
+	"webhook":    chat.WebhookChannelType,
+	"вебхук":     chat.WebhookChannelType,
+}
+
+// streamHosts are hosts of stream pages which contain channel name in the first segment of path.
+var streamHosts = map[string]chat.ChannelType{
+	"twitch.tv":       chat.TwitchChannelType,
+	"m.twitch.tv":     chat.TwitchChannelType,
+	"vkplay.live":     chat.VkChannelType,
+	"live.vkplay.ru":  chat.VkChannelType,
+	"live.vkvideo.ru": chat.VkChannelType,
+	"t.me":            chat.TelegramChannelType,
+	"telegram.me":     chat.TelegramChannelType,
+}
+
+// skippedSegments are path segments before channel name in URLs like twitch.tv/popout/name/chat or t.me/s/name.
+var skippedSegments = map[string]bool{"popout": true, "s": true}
+
+var twitchLoginRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]{3,25}$`)
+
+func lookupPlatform(name string) (chat.ChannelType, bool) {
+	platform, ok := platformAliases[strings.ToLower(name)]
+	return platform, ok
+}
+
+// parseChannelsInput parses one or several channels separated by commas or line breaks.
+// Every channel is "*платформа* *ник*", "*платформа*:*ник*" or URL of a stream.
+// Error text is ready to be shown to user in lang.
+func parseChannelsInput(text string, lang i18n.Language) ([]chat.Channel, error) {
+	var channels []chat.Channel
+	for _, entry := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ';' || r == '\n' }) {
+		fields := strings.Fields(entry)
+		for i := 0; i < len(fields); i++ {
+			var channel chat.Channel
+			var err error
+			if platform, ok := lookupPlatform(fields[i]); ok {
+				if i+1 == len(fields) {
+					return nil, errors.New(lang.T("channels.no_name_after_platform", fields[i]))
+				}
+				channel, err = newChannel(platform, fields[i+1], lang)
+				i++
+			} else {
+				channel, err = parseChannelWord(fields[i], lang)
+			}
+			if err != nil {
+				return nil, err
+			}
+			channels = append(channels, channel)
+		}
+	}
+
+	if len(channels) == 0 {
+		return nil, errors.New(lang.T("channels.not_found"))
+	}
+	return channels, nil
+}
+
+// parseChannelWord parses channel written without spaces: URL or "*платформа*:*ник*".
+func parseChannelWord(word string, lang i18n.Language) (chat.Channel, error) {
+	if channel, ok, err := parseChannelURL(word, lang); ok {
+		return channel, err
+	}
+
+	if prefix, name, ok := strings.Cut(word, ":"); ok {
+		if platform, ok := lookupPlatform(prefix); ok {
+			return newChannel(platform, name, lang)
+		}
+	}
+
+	return chat.Channel{}, errors.New(lang.T("channels.not_understood", word, AvailbalePlatforms()))
+}
+
+// parseChannelURL recognizes links to streams and chats. Returns false if word is not such link.
+func parseChannelURL(word string, lang i18n.Language) (chat.Channel, bool, error) {
+	lower := strings.ToLower(word)
+	if strings.HasPrefix(lower, "irc://") || strings.HasPrefix(lower, "ircs://") {
+		channel, err := newChannel(chat.IRCChannelType, word, lang)
+		return channel, true, err
+	}
+
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		host, _, _ := strings.Cut(lower, "/")
+		if _, ok := streamHosts[strings.TrimPrefix(host, "www.")]; !ok {
+			return chat.Channel{}, false, nil
+		}
+		word = "https://" + word
+	}
+
+	u, err := url.Parse(word)
+	if err != nil {
+		return chat.Channel{}, false, nil
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+
+	if host == "matrix.to" {
+		// matrix.to links keep room in fragment: https://matrix.to/#/#room:server.
+		room, _, _ := strings.Cut(strings.TrimPrefix(u.Fragment, "/"), "?")
+		channel, err := newChannel(chat.MatrixChannelType, room, lang)
+		return channel, true, err
+	}
+
+	platform, ok := streamHosts[host]
+	if !ok {
+		return chat.Channel{}, false, nil
+	}
+
+	var name string
+	for _, segment := range strings.Split(strings.Trim(u.Path, "/"), "/") {
+		if segment != "" && !skippedSegments[segment] {
+			name = segment
+			break
+		}
+	}
+	if name == "" {
+		return chat.Channel{}, true, errors.New(lang.T("channels.no_name_in_url", word))
+	}
+	if platform == chat.TelegramChannelType {
+		name = "@" + name
+	}
+
+	channel, err := newChannel(platform, name, lang)
+	return channel, true, err
+}
+
+// newChannel checks name of channel on platform.
+func newChannel(platform chat.ChannelType, name string, lang i18n.Language) (chat.Channel, error) {
+	if name == "" {
+		return chat.Channel{}, errors.New(lang.T("channels.no_name", PlatformsTypes[platform]))
+	}
+	if platform == chat.TwitchChannelType {
+		if !twitchLoginRegexp.MatchString(name) {
+			return chat.Channel{}, errors.New(lang.T("channels.bad_twitch_login", name))
+		}
+		name = strings.ToLower(name)
+	}
+	channel := chat.Channel{Type: platform, Name: name}
+	if platform == chat.WebhookChannelType {
+		webhook, err := chat.NewWebhookChannel(name)
+		if err != nil {
+			return chat.Channel{}, errors.New(lang.T("channels.unavailable", PlatformsTypes[platform], err))
+		}
+		channel = webhook
+	}
+	if err := chat.CheckChannel(channel); err != nil {
+		return chat.Channel{}, errors.New(lang.T("channels.unavailable", channelTitle(channel), err))
+	}
+	return channel, nil
+}
@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/i18n"
+)
+
+func TestParseChannelsInput(t *testing.T) {
+	twitch := chat.Channel{Type: chat.TwitchChannelType, Name: "streamer"}
+	vk := chat.Channel{Type: chat.VkChannelType, Name: "streamer"}
+	telegram := chat.Channel{Type: chat.TelegramChannelType, Name: "@streamer"}
+	irc := chat.Channel{Type: chat.IRCChannelType, Name: "ircs://irc.libera.chat/#streamer"}
+
+	tests := []struct {
+		input   string
+		want    []chat.Channel
+		wantErr bool
+	}{
+		{input: "twitch streamer", want: []chat.Channel{twitch}},
+		{input: "Twitch Streamer", want: []chat.Channel{twitch}},
+		{input: "твич streamer", want: []chat.Channel{twitch}},
+		{input: "tw:streamer", want: []chat.Channel{twitch}},
+		{input: "vk streamer", want: []chat.Channel{vk}},
+		{input: "tg @streamer", want: []chat.Channel{telegram}},
+		{input: "https://www.twitch.tv/streamer", want: []chat.Channel{twitch}},
+		{input: "twitch.tv/popout/streamer/chat", want: []chat.Channel{twitch}},
+		{input: "https://live.vkvideo.ru/streamer", want: []chat.Channel{vk}},
+		{input: "t.me/s/streamer", want: []chat.Channel{telegram}},
+		{input: "ircs://irc.libera.chat/#streamer", want: []chat.Channel{irc}},
+		{input: "twitch streamer, vk streamer\ntg @streamer", want: []chat.Channel{twitch, vk, telegram}},
+		{input: "twitch streamer vk streamer", want: []chat.Channel{twitch, vk}},
+		{input: "twitch", wantErr: true},
+		{input: "twitch s", wantErr: true},
+		{input: "youtube streamer", wantErr: true},
+		{input: "https://twitch.tv/", wantErr: true},
+		{input: ", ;", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseChannelsInput(tt.input, i18n.English)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseChannelsInput(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseChannelsInput(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// addChatsHandler attaches channels to the running combined chat: /add *chats*.
+func (tb *TelegramBot) addChatsHandler(msgReq *tgbotapi.Message, stat *status) error {
+	args := strings.TrimSpace(msgReq.CommandArguments())
+	if args == "" {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("combining.add_usage"))
+	}
+
+	combined := stat.currentCombined()
+	if combined == nil {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("combining.starting"))
+	}
+
+	channels, err := parseChannelsInput(args, stat.language())
+	if err != nil {
+		return tb.sendMsg(msgReq.Chat.ID, err.Error())
+	}
+	tb.keepChannelSecrets(msgReq, channels, stat.language())
+
+	var lines []string
+	for _, channel := range channels {
+		if hasChannel(stat.channels, channel) {
+			lines = append(lines, stat.t("combining.already_added", channelTitle(channel)))
+			continue
+		}
+		err := combined.Add(channel)
+		switch {
+		case errors.Is(err, chat.ErrChannelAlreadyAdded):
+			lines = append(lines, stat.t("combining.already_added", channelTitle(channel)))
+		case err != nil:
+			lines = append(lines, stat.t("combining.add_failed", channelTitle(channel), err))
+		default:
+			stat.channels = append(stat.channels, channel)
+			lines = append(lines, stat.t("combining.added", channelTitle(channel)))
+		}
+	}
+	return tb.sendMsg(msgReq.Chat.ID, strings.Join(lines, "\n"))
+}
+
+// removeChatsHandler detaches channels from the running combined chat: /remove *numbers or chats*.
+func (tb *TelegramBot) removeChatsHandler(msgReq *tgbotapi.Message, stat *status) error {
+	args := strings.TrimSpace(msgReq.CommandArguments())
+	if args == "" {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("combining.remove_usage", channelsList(stat.channels)))
+	}
+
+	combined := stat.currentCombined()
+	if combined == nil {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("combining.starting"))
+	}
+
+	channels, err := parseRemovedChannels(args, stat.channels, stat.language())
+	if err != nil {
+		return tb.sendMsg(msgReq.Chat.ID, err.Error())
+	}
+
+	var lines []string
+	for _, channel := range channels {
+		if err := combined.Remove(channel); err != nil {
+			lines = append(lines, stat.t("combining.not_added", channelTitle(channel)))
+			continue
+		}
+		stat.channels = removeChannel(stat.channels, channel)
+		lines = append(lines, stat.t("combining.removed", channelTitle(channel)))
+	}
+	if len(stat.channels) == 0 {
+		lines = append(lines, stat.t("combining.all_removed"))
+	}
+	return tb.sendMsg(msgReq.Chat.ID, strings.Join(lines, "\n"))
+}
+
+// parseRemovedChannels parses positions of channels in the list or the channels themselves.
+func parseRemovedChannels(args string, channels []chat.Channel, lang i18n.Language) ([]chat.Channel, error) {
+	var positions []chat.Channel
+	for _, field := range strings.Fields(strings.ReplaceAll(args, ",", " ")) {
+		if _, err := strconv.Atoi(field); err != nil {
+			return parseChannelsInput(args, lang)
+		}
+		channel, err := channelByPosition(field, channels, lang)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, channel)
+	}
+	return positions, nil
+}
+
+func removeChannel(channels []chat.Channel, removed chat.Channel) []chat.Channel {
+	result := channels[:0]
+	for _, channel := range channels {
+		if channel != removed {
+			result = append(result, channel)
+		}
+	}
+	return result
+}
+
+func (tb *TelegramBot) listChatsHandler(msgReq *tgbotapi.Message, stat *status) error {
+	if len(stat.channels) == 0 {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("combining.no_chats"))
+	}
+	return tb.sendMsg(msgReq.Chat.ID, stat.t("combining.list", channelsList(stat.channels)))
+}
+
+func (stat *status) currentCombined() *chat.CombinedChat {
+	stat.mu.Lock()
+	defer stat.mu.Unlock()
+	return stat.combined
+}
+
+func (stat *status) setCombined(combined *chat.CombinedChat) {
+	stat.mu.Lock()
+	defer stat.mu.Unlock()
+	stat.combined = combined
+}
+
+func (stat *status) showLinks() bool {
+	stat.mu.Lock()
+	defer stat.mu.Unlock()
+	return stat.links
+}
+
+func (stat *status) setLinks(links bool) {
+	stat.mu.Lock()
+	defer stat.mu.Unlock()
+	stat.links = links
+}
+
+// linksHandler shows or changes whether authors link to their channels: /links [on|off].
+func (tb *TelegramBot) linksHandler(msgReq *tgbotapi.Message, stat *status) error {
+	switch strings.TrimSpace(msgReq.CommandArguments()) {
+	case "":
+		if stat.showLinks() {
+			return tb.sendMsg(msgReq.Chat.ID, stat.t("links.on"))
+		}
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("links.off"))
+	case "on":
+		stat.setLinks(true)
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("links.enabled"))
+	case "off":
+		stat.setLinks(false)
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("links.disabled"))
+	default:
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("links.usage"))
+	}
+}
@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// credential is an account token stored in one of sessions of the chat.
+type credential struct {
+	session *status
+	// index is the position of the receiver in the session.
+	index   int
+	channel chat.Channel
+}
+
+// storedCredentials returns tokens of all sessions of the chat in order of sessions.
+func storedCredentials(d *dialogue) []credential {
+	var credentials []credential
+	for _, stat := range d.sessions {
+		targets := forwardingTargets(stat)
+		for i, receiver := range stat.receivers {
+			if receiver.token == "" || i >= len(targets) {
+				continue
+			}
+			credentials = append(credentials, credential{session: stat, index: i, channel: targets[i]})
+		}
+	}
+	return credentials
+}
+
+// credentialsHandler lists tokens stored in sessions of the chat: /credentials.
+func (tb *TelegramBot) credentialsHandler(msgReq *tgbotapi.Message, d *dialogue) error {
+	credentials := storedCredentials(d)
+	if len(credentials) == 0 {
+		return tb.sendMsg(msgReq.Chat.ID, d.t("credentials.none"))
+	}
+
+	lines := make([]string, len(credentials))
+	for i, c := range credentials {
+		receiver := c.session.receivers[c.index]
+		lines[i] = d.t("credentials.item", i+1, channelTitle(c.channel), receiver.senderName, maskSecret(receiver.token), c.session.sessionName())
+	}
+	return tb.sendMsg(msgReq.Chat.ID, d.t("credentials.list", strings.Join(lines, "\n")))
+}
+
+// revokeHandler removes stored token and stops the session using it: /revoke *number*.
+func (tb *TelegramBot) revokeHandler(msgReq *tgbotapi.Message, d *dialogue) error {
+	credentials := storedCredentials(d)
+	number, err := strconv.Atoi(strings.TrimSpace(msgReq.CommandArguments()))
+	if err != nil || number < 1 || number > len(credentials) {
+		return tb.sendMsg(msgReq.Chat.ID, d.t("credentials.revoke_usage"))
+	}
+
+	c := credentials[number-1]
+	stat := c.session
+	stage := stat.currentStage()
+	stopped := stage == WorkingForwardingStage
+	stat.stopSession()
+	if stopped || stage == PendingTokensStage {
+		stat.setStage(NotWorkingStage)
+	}
+	stat.receivers[c.index].token = ""
+
+	text := d.t("credentials.revoked", channelTitle(c.channel))
+	if stopped {
+		text += d.t("credentials.session_stopped", stat.sessionName())
+	}
+	return tb.sendMsg(msgReq.Chat.ID, text)
+}
+
+// deleteCredentialsMessage removes message with token from the chat history.
+func (tb *TelegramBot) deleteCredentialsMessage(msgReq *tgbotapi.Message, lang i18n.Language) {
+	if _, err := tb.bot.Request(tgbotapi.NewDeleteMessage(msgReq.Chat.ID, msgReq.MessageID)); err != nil {
+		_ = tb.sendMsg(msgReq.Chat.ID, lang.T("credentials.delete_failed"))
+	}
+}
@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// emotesHandler shows and changes table of emote translation: /emotes [add *mapping* | remove *emote*].
+func (tb *TelegramBot) emotesHandler(msgReq *tgbotapi.Message, stat *status) error {
+	action, args, _ := strings.Cut(strings.TrimSpace(msgReq.CommandArguments()), " ")
+	switch action {
+	case "":
+		return tb.sendMsg(msgReq.Chat.ID, fmt.Sprintf("%s\n\n%s", emotesList(stat.emotes, stat.language()), stat.t("emotes.help")))
+	case "add":
+		mapping, err := chat.ParseEmoteMapping(args)
+		if err != nil {
+			return tb.sendMsg(msgReq.Chat.ID, stat.t("emotes.invalid", err, stat.t("emotes.help")))
+		}
+		stat.emotes.Set(mapping)
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("emotes.saved", mapping))
+	case "remove":
+		if !stat.emotes.Remove(strings.TrimSpace(args)) {
+			return tb.sendMsg(msgReq.Chat.ID, stat.t("emotes.not_found"))
+		}
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("emotes.removed"))
+	default:
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("emotes.unknown", stat.t("emotes.help")))
+	}
+}
+
+func emotesList(emotes *chat.EmoteTable, lang i18n.Language) string {
+	mappings := emotes.Mappings()
+	if len(mappings) == 0 {
+		return lang.T("emotes.empty")
+	}
+	lines := make([]string, len(mappings))
+	for i, mapping := range mappings {
+		lines[i] = mapping.String()
+	}
+	return lang.T("emotes.table", strings.Join(lines, "\n"))
+}
@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// filterHandler shows and changes rules of the session filter: /filter [add *rule* | remove *number* | clear].
+// Rules can be changed both during setup and while session works.
+func (tb *TelegramBot) filterHandler(msgReq *tgbotapi.Message, stat *status) error {
+	action, args, _ := strings.Cut(strings.TrimSpace(msgReq.CommandArguments()), " ")
+	switch action {
+	case "":
+		return tb.sendMsg(msgReq.Chat.ID, fmt.Sprintf("%s\n\n%s", filterRulesList(stat.filter, stat.language()), stat.t("filter.help")))
+	case "add":
+		rule, err := chat.ParseRule(args)
+		if err != nil {
+			return tb.sendMsg(msgReq.Chat.ID, stat.t("filter.invalid_rule", err, stat.t("filter.help")))
+		}
+		stat.filter.Add(rule)
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("filter.added", filterRulesList(stat.filter, stat.language())))
+	case "remove":
+		index, err := strconv.Atoi(strings.TrimSpace(args))
+		if err != nil || !stat.filter.Remove(index-1) {
+			return tb.sendMsg(msgReq.Chat.ID, stat.t("filter.no_rule"))
+		}
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("filter.removed", filterRulesList(stat.filter, stat.language())))
+	case "clear":
+		stat.filter.Clear()
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("filter.cleared"))
+	default:
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("filter.unknown", stat.t("filter.help")))
+	}
+}
+
+func filterRulesList(filter *chat.Filter, lang i18n.Language) string {
+	rules := filter.Rules()
+	if len(rules) == 0 {
+		return lang.T("filter.empty")
+	}
+	lines := make([]string, len(rules))
+	for i, rule := range rules {
+		lines[i] = fmt.Sprintf("%d. %s", i+1, rule)
+	}
+	return lang.T("filter.rules", strings.Join(lines, "\n"))
+}
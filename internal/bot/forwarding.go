@@ -0,0 +1,398 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func (tb *TelegramBot) pendingForwardingChatsHandler(msgReq *tgbotapi.Message, stat *status) error {
+	if msgReq.Text == "/done" {
+		return tb.finishForwardingChats(msgReq.Chat.ID, stat)
+	}
+	return tb.addChannel(msgReq, stat)
+}
+
+func (tb *TelegramBot) finishForwardingChats(chatID int64, stat *status) error {
+	if len(stat.channels) < 2 {
+		return tb.sendMsg(chatID, stat.t("forwarding.need_two_chats"))
+	}
+	stat.setStage(PendingRoutesStage)
+	return tb.sendWizard(chatID, stat, routesWizardText(stat), routesKeyboard(stat))
+}
+
+func (tb *TelegramBot) pendingRoutesHandler(msgReq *tgbotapi.Message, stat *status) error {
+	switch msgReq.Text {
+	case "/all":
+		addAllRoutes(stat)
+		return tb.finishRoutes(msgReq.Chat.ID, stat)
+	case "/done":
+		return tb.finishRoutes(msgReq.Chat.ID, stat)
+	}
+
+	var routes []chat.Route
+	for _, input := range strings.Fields(strings.ReplaceAll(msgReq.Text, ",", " ")) {
+		parsed, err := parseRouteInput(input, stat.channels, stat.language())
+		if err != nil {
+			return tb.sendMsg(msgReq.Chat.ID, err.Error())
+		}
+		routes = append(routes, parsed...)
+	}
+
+	var added []string
+	for _, route := range routes {
+		if addRoute(stat, route) {
+			added = append(added, fmt.Sprintf("%s → %s", channelTitle(route.From), channelTitle(route.To)))
+		}
+	}
+	if len(added) == 0 {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("routes.already_added"))
+	}
+
+	return tb.sendWizard(msgReq.Chat.ID, stat,
+		stat.t("wizard.added", strings.Join(added, "\n"), routesWizardText(stat)), routesKeyboard(stat))
+}
+
+func (tb *TelegramBot) finishRoutes(chatID int64, stat *status) error {
+	if len(stat.routes) == 0 {
+		return tb.sendMsg(chatID, stat.t("routes.none"))
+	}
+	tb.clearWizard(chatID, stat)
+	return tb.requestNextReceiver(chatID, stat)
+}
+
+func addAllRoutes(stat *status) {
+	for _, from := range stat.channels {
+		for _, to := range stat.channels {
+			addRoute(stat, chat.Route{From: from, To: to})
+		}
+	}
+}
+
+// parseRouteInput parses route in format "1>2", "1<2" or "1<>2", where numbers are positions of channels.
+// Error text is ready to be shown to user in lang.
+func parseRouteInput(input string, channels []chat.Channel, lang i18n.Language) ([]chat.Route, error) {
+	var separator string
+	for _, sep := range []string{"<>", ">", "<"} {
+		if strings.Contains(input, sep) {
+			separator = sep
+			break
+		}
+	}
+	if separator == "" {
+		return nil, errors.New(lang.T("routes.bad_format", input))
+	}
+
+	left, right, _ := strings.Cut(input, separator)
+	first, err := channelByPosition(left, channels, lang)
+	if err != nil {
+		return nil, err
+	}
+	second, err := channelByPosition(right, channels, lang)
+	if err != nil {
+		return nil, err
+	}
+	if first == second {
+		return nil, errors.New(lang.T("routes.same_chat", input))
+	}
+
+	var routes []chat.Route
+	if separator != "<" {
+		routes = append(routes, chat.Route{From: first, To: second})
+	}
+	if separator != ">" {
+		routes = append(routes, chat.Route{From: second, To: first})
+	}
+
+	for _, route := range routes {
+		if route.To.Type == chat.WebhookChannelType {
+			return nil, errors.New(lang.T("routes.to_webhook", channelTitle(route.To)))
+		}
+	}
+	return routes, nil
+}
+
+func channelByPosition(input string, channels []chat.Channel, lang i18n.Language) (chat.Channel, error) {
+	position, err := strconv.Atoi(input)
+	if err != nil || position < 1 || position > len(channels) {
+		return chat.Channel{}, errors.New(lang.T("routes.bad_position", input, len(channels)))
+	}
+	return channels[position-1], nil
+}
+
+// addRoute adds route if it is new and valid. Returns false if route wasn't added.
+func addRoute(stat *status, route chat.Route) bool {
+	if route.From == route.To || route.To.Type == chat.WebhookChannelType || hasRoute(stat, route) {
+		return false
+	}
+	stat.routes = append(stat.routes, route)
+	return true
+}
+
+func channelsList(channels []chat.Channel) string {
+	lines := make([]string, len(channels))
+	for i, channel := range channels {
+		lines[i] = fmt.Sprintf("%d. %s", i+1, channelTitle(channel))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// pendingTokensHandler reads account of the next target channel and checks it on the platform.
+func (tb *TelegramBot) pendingTokensHandler(msgReq *tgbotapi.Message, stat *status) error {
+	channel := forwardingTargets(stat)[len(stat.receivers)]
+	spec, _ := chat.Credentials(channel.Type)
+
+	reciever, err := parseCredentials(msgReq.Text, channel, spec, stat.language())
+	tb.deleteCredentialsMessage(msgReq, stat.language())
+	if err != nil {
+		return tb.sendMsg(msgReq.Chat.ID, err.Error())
+	}
+	tb.secrets.Add(reciever.AuthToken)
+
+	_ = tb.sendMsg(msgReq.Chat.ID, stat.t("credentials.checking"))
+	reciever, err = chat.VerifyReciever(reciever)
+	if err != nil && !errors.Is(err, chat.ErrNotVerified) {
+		return tb.sendMsg(msgReq.Chat.ID, credentialsErrorText(err, channel, stat.language()))
+	}
+
+	stat.receivers = append(stat.receivers, recieverInfo{senderName: reciever.SenderName, token: reciever.AuthToken})
+
+	if err != nil {
+		tb.sendMsg(msgReq.Chat.ID, stat.t("credentials.unverified", channelTitle(channel)))
+	} else {
+		tb.sendMsg(msgReq.Chat.ID, stat.t("credentials.saved", channelTitle(channel), reciever.SenderName))
+	}
+	return tb.requestNextReceiver(msgReq.Chat.ID, stat)
+}
+
+// parseCredentials parses account in format expected by platform: "*token*" or "*name* *token*" if name is detected from token,
+// "*name*" or "*name* *password*" if password is optional. Error text is ready to be shown to user in lang.
+func parseCredentials(text string, channel chat.Channel, spec chat.CredentialsSpec, lang i18n.Language) (chat.Reciever, error) {
+	input := strings.Fields(text)
+	reciever := chat.Reciever{Channel: channel}
+	switch {
+	case spec.Name && len(input) == 1:
+		reciever.SenderName = input[0]
+	case len(input) == 2:
+		reciever.SenderName, reciever.AuthToken = input[0], input[1]
+	case !spec.Name && len(input) == 1:
+		reciever.AuthToken = input[0]
+	default:
+		return reciever, errors.New(lang.T("credentials.bad_format", credentialsFormat(spec, lang)))
+	}
+	return reciever, nil
+}
+
+func credentialsFormat(spec chat.CredentialsSpec, lang i18n.Language) string {
+	if spec.Name {
+		return lang.T("credentials.name_format")
+	}
+	return lang.T("credentials.token_format")
+}
+
+// credentialsErrorText explains why account can't be used.
+func credentialsErrorText(err error, channel chat.Channel, lang i18n.Language) string {
+	var mismatch *chat.AccountMismatchError
+	switch {
+	case errors.As(err, &mismatch):
+		return lang.T("credentials.account_mismatch", mismatch.Account, mismatch.Name)
+	case errors.Is(err, chat.ErrInvalidToken):
+		return lang.T("credentials.invalid_token", PlatformsTypes[channel.Type])
+	case errors.Is(err, chat.ErrMissingScope):
+		return lang.T("credentials.missing_scope")
+	case errors.Is(err, chat.ErrNoToken):
+		return lang.T("credentials.no_token", credentialsFormat(chat.CredentialsSpec{}, lang))
+	default:
+		return lang.T("credentials.verify_failed", err)
+	}
+}
+
+// forwardingTargets returns channels in which messages will be sent, in order of receivers.
+func forwardingTargets(stat *status) []chat.Channel {
+	var targets []chat.Channel
+	seen := make(map[chat.Channel]struct{})
+	for _, route := range stat.routes {
+		if _, ok := seen[route.To]; ok {
+			continue
+		}
+		seen[route.To] = struct{}{}
+		targets = append(targets, route.To)
+	}
+	return targets
+}
+
+// requestNextReceiver asks for account of the next target channel or starts forwarding if all accounts are known.
+// Telegram groups don't need any account, messages there are sent by the bot itself.
+func (tb *TelegramBot) requestNextReceiver(chatID int64, stat *status) error {
+	targets := forwardingTargets(stat)
+	for len(stat.receivers) < len(targets) && targets[len(stat.receivers)].Type == chat.TelegramChannelType {
+		stat.receivers = append(stat.receivers, recieverInfo{})
+	}
+
+	if len(stat.receivers) == len(targets) {
+		stop := stat.startSession(WorkingForwardingStage)
+		return tb.startForwarding(chatID, stat, stop)
+	}
+
+	channel := targets[len(stat.receivers)]
+	stat.setStage(PendingTokensStage)
+	spec, _ := chat.Credentials(channel.Type)
+	if spec.Name {
+		return tb.sendMsg(chatID, stat.t("credentials.request_name",
+			channelTitle(channel), credentialsFormat(spec, stat.language())))
+	}
+	return tb.sendMsg(chatID, stat.t("credentials.request_token", PlatformsTypes[channel.Type], channel.Name))
+}
+
+func (tb *TelegramBot) workingForwardingHandler(msgReq *tgbotapi.Message, stat *status) error {
+	// Router is set when forwarding is started, which can take a while because of logins.
+	router := stat.currentRouter()
+	switch msgReq.Text {
+	case "/stop":
+		stat.stopSession()
+		stat.setStage(NotWorkingStage)
+		var echoes chat.EchoStats
+		if router != nil {
+			echoes = router.EchoStats()
+		}
+		return tb.sendMsg(msgReq.Chat.ID, stat.language().N("forwarding.stopped", int(echoes.ByAuthor+echoes.ByContent)))
+	default:
+		if router == nil {
+			return tb.sendMsg(msgReq.Chat.ID, stat.t("forwarding.starting"))
+		}
+		switch msgReq.Command() {
+		case "template":
+			return tb.forwardingTemplateHandler(msgReq, stat)
+		case "long":
+			return tb.lengthPolicyHandler(msgReq, stat)
+		case "moderation":
+			return tb.moderationHandler(msgReq, stat)
+		case "raw":
+			return tb.rawHandler(msgReq, stat)
+		case "status":
+			return tb.statusHandler(msgReq, stat)
+		}
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("forwarding.commands"))
+	}
+}
+
+func (stat *status) currentRouter() *chat.Router {
+	stat.mu.Lock()
+	defer stat.mu.Unlock()
+	return stat.router
+}
+
+func (stat *status) setRouter(router *chat.Router) {
+	stat.mu.Lock()
+	defer stat.mu.Unlock()
+	stat.router = router
+}
+
+// attachRouter sets router of the session started with stop. It returns false if the session was stopped meanwhile,
+// the router stops by itself then.
+func (stat *status) attachRouter(router *chat.Router, stop <-chan struct{}) bool {
+	stat.mu.Lock()
+	defer stat.mu.Unlock()
+	if !stat.runningLocked(stop) {
+		return false
+	}
+	stat.router = router
+	return true
+}
+
+// startForwarding starts router of the session until stop is closed. If it can't be started, the session is stopped
+// and the chat is told why.
+func (tb *TelegramBot) startForwarding(chatID int64, stat *status, stop <-chan struct{}) error {
+	_ = tb.sendMsg(chatID, stat.t("start.starting"))
+	stat.setRouter(nil)
+
+	targets := forwardingTargets(stat)
+	recievers := make([]chat.Reciever, len(targets))
+	for i, target := range targets {
+		recievers[i] = chat.Reciever{
+			Channel:    target,
+			AuthToken:  stat.receivers[i].token,
+			SenderName: stat.receivers[i].senderName,
+		}
+	}
+
+	router, err := chat.NewRouter(recievers, stat.routes)
+	if err == nil {
+		router.SetTemplate(stat.currentTemplate())
+		router.SetLanguage(stat.language())
+		router.SetFilter(stat.filter)
+		router.SetEmotes(stat.emotes)
+		if stat.savedRouter != nil {
+			err = router.ApplySettings(*stat.savedRouter)
+			stat.savedRouter = nil
+		}
+	}
+	if err == nil {
+		err = router.Start(stop)
+	}
+	stat.started = time.Now()
+	if err != nil {
+		stat.abortSession(stop)
+		return tb.sendMsg(chatID, stat.t("forwarding.start_failed", err))
+	}
+	// Senders log in for a while, the session may be stopped before that.
+	if !stat.attachRouter(router, stop) {
+		return nil
+	}
+
+	_ = tb.sendMsg(chatID, stat.t("start.done"))
+
+	return nil
+}
+
+var lengthPolicies = map[string]chat.LengthPolicy{
+	"truncate": chat.TruncateLongMessages,
+	"split":    chat.SplitLongMessages,
+}
+
+// lengthPolicyNames are catalog keys of length policy descriptions.
+var lengthPolicyNames = map[chat.LengthPolicy]string{
+	chat.TruncateLongMessages: "long.truncate",
+	chat.SplitLongMessages:    "long.split",
+}
+
+// lengthPolicyHandler shows or changes what is done with messages longer than limit of destination:
+// /long [routes] truncate|split.
+func (tb *TelegramBot) lengthPolicyHandler(msgReq *tgbotapi.Message, stat *status) error {
+	router := stat.currentRouter()
+	if router == nil {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("forwarding.starting"))
+	}
+	args := strings.TrimSpace(msgReq.CommandArguments())
+	if args == "" {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("long.summary",
+			channelsList(stat.channels), routesSummary(stat, func(route chat.Route) string {
+				return stat.t(lengthPolicyNames[router.LengthPolicy(route)])
+			})))
+	}
+
+	routes, raw := splitRoutesArgs(args, stat)
+	policy, ok := lengthPolicies[raw]
+	if !ok {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("long.unknown"))
+	}
+
+	if len(routes) == 0 {
+		router.SetLengthPolicy(policy)
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("routes.set_all", stat.t(lengthPolicyNames[policy])))
+	}
+
+	if text, missing := missingRouteMessage(stat, routes); missing {
+		return tb.sendMsg(msgReq.Chat.ID, text)
+	}
+	for _, route := range routes {
+		router.SetRouteLengthPolicy(route, policy)
+	}
+	return tb.sendMsg(msgReq.Chat.ID, stat.t("routes.set_selected", stat.t(lengthPolicyNames[policy])))
+}
@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/i18n"
+)
+
+func TestParseRouteInput(t *testing.T) {
+	twitch := chat.Channel{Type: chat.TwitchChannelType, Name: "streamer"}
+	vk := chat.Channel{Type: chat.VkChannelType, Name: "streamer"}
+	webhook := chat.Channel{Type: chat.WebhookChannelType, Name: "hook"}
+	channels := []chat.Channel{twitch, vk, webhook}
+
+	tests := []struct {
+		input   string
+		want    []chat.Route
+		wantErr bool
+	}{
+		{input: "1>2", want: []chat.Route{{From: twitch, To: vk}}},
+		{input: "1<2", want: []chat.Route{{From: vk, To: twitch}}},
+		{input: "1<>2", want: []chat.Route{{From: twitch, To: vk}, {From: vk, To: twitch}}},
+		{input: "3>1", want: []chat.Route{{From: webhook, To: twitch}}},
+		{input: "1>3", wantErr: true},
+		{input: "1<>3", wantErr: true},
+		{input: "1>1", wantErr: true},
+		{input: "0>1", wantErr: true},
+		{input: "1>4", wantErr: true},
+		{input: "a>b", wantErr: true},
+		{input: "1-2", wantErr: true},
+		{input: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseRouteInput(tt.input, channels, i18n.English)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRouteInput(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRouteInput(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/MrMamka/combchats/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// languageHandler shows or changes language of the chat: /language [ru|en].
+// Running forwarding sessions switch language of notifications at once.
+func (tb *TelegramBot) languageHandler(msgReq *tgbotapi.Message, d *dialogue) error {
+	code := strings.TrimSpace(msgReq.CommandArguments())
+	if code == "" {
+		return tb.sendMsg(msgReq.Chat.ID, d.t("language.current", d.language().Name(), languagesList()))
+	}
+
+	lang, ok := i18n.Parse(code)
+	if !ok {
+		return tb.sendMsg(msgReq.Chat.ID, d.t("language.unknown", code, languagesList()))
+	}
+	d.setLanguage(lang)
+	for _, stat := range d.sessions {
+		if router := stat.currentRouter(); router != nil && stat.currentStage() == WorkingForwardingStage {
+			router.SetLanguage(lang)
+		}
+	}
+	return tb.sendMsg(msgReq.Chat.ID, d.t("language.changed", lang.Name()))
+}
+
+func languagesList() string {
+	codes := make([]string, len(i18n.Languages))
+	for i, lang := range i18n.Languages {
+		codes[i] = string(lang) + " - " + lang.Name()
+	}
+	return strings.Join(codes, ", ")
+}
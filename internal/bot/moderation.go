@@ -0,0 +1,53 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+var moderationPolicies = map[string]chat.ModerationPolicy{
+	"off":    chat.NoModeration,
+	"delete": chat.DeleteCopies,
+	"ban":    chat.MirrorBans,
+}
+
+// moderationPolicyNames are catalog keys of moderation policy descriptions.
+var moderationPolicyNames = map[chat.ModerationPolicy]string{
+	chat.NoModeration: "moderation.off",
+	chat.DeleteCopies: "moderation.delete",
+	chat.MirrorBans:   "moderation.ban",
+}
+
+// moderationHandler shows or changes which moderation actions are repeated on routes: /moderation [routes] off|delete|ban.
+func (tb *TelegramBot) moderationHandler(msgReq *tgbotapi.Message, stat *status) error {
+	router := stat.currentRouter()
+	if router == nil {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("forwarding.starting"))
+	}
+	args := strings.TrimSpace(msgReq.CommandArguments())
+	if args == "" {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("moderation.summary",
+			channelsList(stat.channels), routesSummary(stat, func(route chat.Route) string {
+				return stat.t(moderationPolicyNames[router.Moderation(route)])
+			})))
+	}
+
+	routes, raw := splitRoutesArgs(args, stat)
+	policy, ok := moderationPolicies[raw]
+	if !ok {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("moderation.unknown"))
+	}
+
+	if len(routes) == 0 {
+		routes = stat.routes
+	}
+	if text, missing := missingRouteMessage(stat, routes); missing {
+		return tb.sendMsg(msgReq.Chat.ID, text)
+	}
+	for _, route := range routes {
+		router.SetRouteModeration(route, policy)
+	}
+	return tb.sendMsg(msgReq.Chat.ID, stat.t("moderation.set", stat.t(moderationPolicyNames[policy])))
+}
@@ -0,0 +1,315 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/i18n"
+	"github.com/MrMamka/combchats/internal/store"
+)
+
+// dialogueState is the saved form of dialogue.
+type dialogueState struct {
+	Current  string
+	Created  int
+	Language i18n.Language `json:",omitempty"`
+	Sessions []sessionState
+}
+
+// sessionState is the saved form of status. Wizard messages are not saved, a resumed wizard is continued by text.
+type sessionState struct {
+	Name      string
+	Stage     Stage
+	Channels  []chat.Channel
+	Routes    []chat.Route
+	Receivers []receiverState
+	Template  string
+	Filter    []string
+	Emotes    []string
+	Router    *chat.RouterSettings `json:",omitempty"`
+	Links     bool                 `json:",omitempty"`
+	Secrets   []channelSecretState `json:",omitempty"`
+}
+
+// channelSecretState is the encrypted secret of a channel, channels are saved without secrets.
+type channelSecretState struct {
+	Channel chat.Channel
+	Secret  string
+}
+
+type receiverState struct {
+	SenderName string
+	Token      string
+}
+
+// SetStoreFromEnv opens store of sessions in the file from env. Sessions are not saved if env is empty.
+// Tokens are encrypted with the key from keyEnv, which is required if sessions are saved.
+func (tb *TelegramBot) SetStoreFromEnv(env, keyEnv string) error {
+	path := os.Getenv(env)
+	if path == "" {
+		return nil
+	}
+	cipher, err := store.NewCipher(os.Getenv(keyEnv))
+	if err != nil {
+		return fmt.Errorf("%s is required to encrypt tokens in state store: %w", keyEnv, err)
+	}
+	tb.cipher = cipher
+
+	s, err := store.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open state store %s: %w", path, err)
+	}
+	tb.store = s
+	return nil
+}
+
+// saveDialogue saves all sessions of the chat. Errors are only logged, the bot keeps working without store.
+func (tb *TelegramBot) saveDialogue(chatID int64, d *dialogue) {
+	if tb.store == nil {
+		return
+	}
+	tb.saveMu.Lock()
+	defer tb.saveMu.Unlock()
+
+	state, err := d.state(tb.cipher)
+	var data []byte
+	if err == nil {
+		data, err = json.Marshal(state)
+	}
+	if err == nil {
+		err = tb.store.Put(strconv.FormatInt(chatID, 10), data)
+	}
+	if err != nil {
+		log.Printf("Unable to save sessions of chat %d: %v", chatID, err)
+	}
+}
+
+// state returns saved form of dialogue with tokens encrypted by cipher.
+func (d *dialogue) state(cipher *store.Cipher) (dialogueState, error) {
+	d.mu.Lock()
+	sessions := append([]*status(nil), d.sessions...)
+	lang := d.lang
+	d.mu.Unlock()
+
+	state := dialogueState{Current: d.current.sessionName(), Created: d.created, Language: lang}
+	for _, stat := range sessions {
+		sessionState, err := stat.state(cipher)
+		if err != nil {
+			return dialogueState{}, err
+		}
+		state.Sessions = append(state.Sessions, sessionState)
+	}
+	return state, nil
+}
+
+func (s *status) state(cipher *store.Cipher) (sessionState, error) {
+	state := sessionState{
+		Name:     s.sessionName(),
+		Stage:    s.currentStage(),
+		Channels: s.channels,
+		Routes:   s.routes,
+		Template: s.currentTemplate().String(),
+		Links:    s.showLinks(),
+	}
+	for _, receiver := range s.receivers {
+		token, err := cipher.Seal(receiver.token)
+		if err != nil {
+			return sessionState{}, err
+		}
+		state.Receivers = append(state.Receivers, receiverState{SenderName: receiver.senderName, Token: token})
+	}
+	for _, channel := range s.channels {
+		if channel.Secret == "" {
+			continue
+		}
+		secret, err := cipher.Seal(channel.Secret)
+		if err != nil {
+			return sessionState{}, err
+		}
+		state.Secrets = append(state.Secrets, channelSecretState{Channel: channel, Secret: secret})
+	}
+	if s.filter != nil {
+		for _, rule := range s.filter.Rules() {
+			state.Filter = append(state.Filter, rule.String())
+		}
+	}
+	if s.emotes != nil {
+		for _, mapping := range s.emotes.Mappings() {
+			state.Emotes = append(state.Emotes, mapping.String())
+		}
+	}
+	if router := s.currentRouter(); router != nil && s.currentStage() == WorkingForwardingStage {
+		settings := router.Settings()
+		state.Router = &settings
+	}
+	return state, nil
+}
+
+// restoreDialogue creates dialogue from its saved form, tokens are decrypted by cipher. Running sessions are not started.
+func restoreDialogue(state dialogueState, cipher *store.Cipher) (*dialogue, error) {
+	d := &dialogue{created: state.Created, lang: state.Language}
+	for _, sessionState := range state.Sessions {
+		stat, err := restoreSession(sessionState, cipher)
+		if err != nil {
+			return nil, fmt.Errorf("session %s: %w", sessionState.Name, err)
+		}
+		stat.dialogue = d
+		d.sessions = append(d.sessions, stat)
+	}
+
+	if len(d.sessions) == 0 {
+		d.current = d.add("")
+		return d, nil
+	}
+	d.current = d.find(state.Current)
+	if d.current == nil {
+		d.current = d.sessions[0]
+	}
+	return d, nil
+}
+
+func restoreSession(state sessionState, cipher *store.Cipher) (*status, error) {
+	stat := &status{
+		stage:       state.Stage,
+		channels:    state.Channels,
+		routes:      state.Routes,
+		savedRouter: state.Router,
+		name:        state.Name,
+		template:    chat.DefaultTemplate,
+		links:       state.Links,
+		filter:      chat.NewFilter(),
+		emotes:      chat.NewEmoteTable(nil),
+		stop:        make(chan struct{}),
+	}
+	for _, receiver := range state.Receivers {
+		token, err := cipher.Open(receiver.Token)
+		if err != nil {
+			return nil, err
+		}
+		stat.receivers = append(stat.receivers, recieverInfo{senderName: receiver.SenderName, token: token})
+	}
+
+	if err := restoreChannelSecrets(stat, state.Secrets, cipher); err != nil {
+		return nil, err
+	}
+
+	if state.Template != "" {
+		t, err := chat.ParseTemplate(state.Template)
+		if err != nil {
+			return nil, err
+		}
+		stat.template = t
+	}
+	for _, raw := range state.Filter {
+		rule, err := chat.ParseRule(raw)
+		if err != nil {
+			return nil, err
+		}
+		stat.filter.Add(rule)
+	}
+	for _, raw := range state.Emotes {
+		mapping, err := chat.ParseEmoteMapping(raw)
+		if err != nil {
+			return nil, err
+		}
+		stat.emotes.Set(mapping)
+	}
+	return stat, nil
+}
+
+// restoreChannelSecrets returns secrets to channels and routes of the session, which are saved without them.
+func restoreChannelSecrets(stat *status, saved []channelSecretState, cipher *store.Cipher) error {
+	secrets := make(map[chat.Channel]string)
+	for _, secretState := range saved {
+		secret, err := cipher.Open(secretState.Secret)
+		if err != nil {
+			return err
+		}
+		secrets[secretState.Channel] = secret
+	}
+
+	withSecret := func(channel chat.Channel) chat.Channel {
+		if secret, ok := secrets[channel]; ok {
+			channel.Secret = secret
+		}
+		return channel
+	}
+	for i := range stat.channels {
+		stat.channels[i] = withSecret(stat.channels[i])
+	}
+	for i := range stat.routes {
+		stat.routes[i] = chat.Route{From: withSecret(stat.routes[i].From), To: withSecret(stat.routes[i].To)}
+	}
+	if stat.savedRouter != nil {
+		for i := range stat.savedRouter.Routes {
+			route := stat.savedRouter.Routes[i].Route
+			stat.savedRouter.Routes[i].Route = chat.Route{From: withSecret(route.From), To: withSecret(route.To)}
+		}
+	}
+	return nil
+}
+
+// resumeSessions loads saved dialogues and starts sessions which were running before restart.
+func (tb *TelegramBot) resumeSessions() {
+	if tb.store == nil {
+		return
+	}
+	saved, err := tb.store.All()
+	if err != nil {
+		log.Printf("Unable to load saved sessions: %v", err)
+		return
+	}
+
+	for key, data := range saved {
+		chatID, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			continue
+		}
+		var state dialogueState
+		if err := json.Unmarshal(data, &state); err != nil {
+			log.Printf("Unable to load sessions of chat %d: %v", chatID, err)
+			continue
+		}
+		d, err := restoreDialogue(state, tb.cipher)
+		if err != nil {
+			log.Printf("Unable to restore sessions of chat %d: %v", chatID, err)
+			continue
+		}
+		tb.dialogues[chatID] = d
+		for _, stat := range d.sessions {
+			for _, receiver := range stat.receivers {
+				tb.secrets.Add(receiver.token)
+			}
+			for _, channel := range stat.channels {
+				tb.secrets.Add(channel.Secret)
+			}
+		}
+
+		// Starting chats may wait for logins, so sessions start in background and updates are polled meanwhile.
+		for _, stat := range d.sessions {
+			go tb.resumeSession(chatID, stat)
+		}
+	}
+}
+
+func (tb *TelegramBot) resumeSession(chatID int64, stat *status) {
+	stage, stop := stat.stageAndStop()
+	switch stage {
+	case WorkingCombiningStage:
+		_ = tb.sendMsg(chatID, stat.t("resume.working", stat.sessionName()))
+		if err := tb.startChats(chatID, stat, stop); err != nil {
+			tb.saveDialogue(chatID, stat.dialogue)
+		}
+	case WorkingForwardingStage:
+		_ = tb.sendMsg(chatID, stat.t("resume.working", stat.sessionName()))
+		_ = tb.startForwarding(chatID, stat, stop)
+		tb.saveDialogue(chatID, stat.dialogue)
+	case NotWorkingStage:
+	default:
+		_ = tb.sendMsg(chatID, stat.t("resume.setup", stat.sessionName()))
+	}
+}
@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/store"
+)
+
+func TestDialogueStateRoundTrip(t *testing.T) {
+	twitch := chat.Channel{Type: chat.TwitchChannelType, Name: "streamer"}
+	vk := chat.Channel{Type: chat.VkChannelType, Name: "streamer"}
+	webhook := chat.Channel{Type: chat.WebhookChannelType, Name: "hook", Secret: "webhook-token"}
+	routes := []chat.Route{{From: twitch, To: vk}, {From: vk, To: twitch}}
+
+	d := newDialogue()
+	combining := d.current
+	combining.stage = WorkingCombiningStage
+	combining.channels = []chat.Channel{twitch, vk, webhook}
+	combining.setTemplate(chat.MustParseTemplate("[{platform}] {author}: {text}"))
+	combining.setLinks(true)
+	combining.filter = chat.NewFilter()
+	for _, raw := range []string{"deny-author nightbot", "min-length 2"} {
+		rule, err := chat.ParseRule(raw)
+		if err != nil {
+			t.Fatalf("ParseRule(%q) error = %v", raw, err)
+		}
+		combining.filter.Add(rule)
+	}
+
+	forwarding := d.add("relay")
+	forwarding.stage = WorkingForwardingStage
+	forwarding.setTemplate(chat.DefaultTemplate)
+	forwarding.channels = []chat.Channel{twitch, vk}
+	forwarding.routes = routes
+	forwarding.receivers = []recieverInfo{{senderName: "bot", token: "twitch-token"}, {token: "vk-token"}}
+	forwarding.emotes = chat.NewEmoteTable(nil)
+	mapping, err := chat.ParseEmoteMapping("twitch=Kappa vk=kappa")
+	if err != nil {
+		t.Fatalf("ParseEmoteMapping() error = %v", err)
+	}
+	forwarding.emotes.Set(mapping)
+	router, err := chat.NewRouter([]chat.Reciever{{Channel: twitch}, {Channel: vk}}, routes)
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	router.SetLengthPolicy(chat.SplitLongMessages)
+	router.SetRouteTemplate(routes[0], chat.MustParseTemplate("{author} > {text}"))
+	forwarding.router = router
+
+	setup := d.add("setup")
+	setup.stage = PendingRoutesStage
+	setup.setTemplate(chat.DefaultTemplate)
+
+	cipher, err := store.NewCipher("test key")
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	want, err := d.state(cipher)
+	if err != nil {
+		t.Fatalf("state() error = %v", err)
+	}
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	for _, token := range []string{"twitch-token", "webhook-token"} {
+		if bytes.Contains(data, []byte(token)) {
+			t.Errorf("saved state contains %s: %s", token, data)
+		}
+	}
+	var saved dialogueState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	restored, err := restoreDialogue(saved, cipher)
+	if err != nil {
+		t.Fatalf("restoreDialogue() error = %v", err)
+	}
+
+	if restored.current.sessionName() != combining.sessionName() {
+		t.Errorf("current session = %q, want %q", restored.current.sessionName(), combining.sessionName())
+	}
+	if !reflect.DeepEqual(restored.sessions[0].channels, combining.channels) {
+		t.Errorf("restored channels = %+v, want %+v", restored.sessions[0].channels, combining.channels)
+	}
+	if !reflect.DeepEqual(restored.sessions[1].receivers, forwarding.receivers) {
+		t.Errorf("restored receivers = %+v, want %+v", restored.sessions[1].receivers, forwarding.receivers)
+	}
+	got, err := restored.state(cipher)
+	if err != nil {
+		t.Fatalf("state() of restored dialogue error = %v", err)
+	}
+	// Tokens are sealed with random nonces, so they are compared decrypted above.
+	got.Sessions[0].Secrets, want.Sessions[0].Secrets = nil, nil
+	got.Sessions[1].Receivers, want.Sessions[1].Receivers = nil, nil
+	// Router of forwarding session is started on resume, until then its settings are kept aside.
+	got.Sessions[1].Router = restored.sessions[1].savedRouter
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("restored state = %+v\nwant %+v", got, want)
+	}
+}
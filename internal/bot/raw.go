@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"strings"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func rawModeName(raw bool, lang i18n.Language) string {
+	if raw {
+		return lang.T("raw.on")
+	}
+	return lang.T("raw.off")
+}
+
+// rawHandler shows or changes sanitization of forwarded text on routes: /raw [routes] on|off.
+func (tb *TelegramBot) rawHandler(msgReq *tgbotapi.Message, stat *status) error {
+	router := stat.currentRouter()
+	if router == nil {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("forwarding.starting"))
+	}
+	args := strings.TrimSpace(msgReq.CommandArguments())
+	if args == "" {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("raw.summary",
+			channelsList(stat.channels), routesSummary(stat, func(route chat.Route) string {
+				return rawModeName(router.Raw(route), stat.language())
+			})))
+	}
+
+	routes, value := splitRoutesArgs(args, stat)
+	if value != "on" && value != "off" {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("raw.unknown"))
+	}
+	raw := value == "on"
+
+	if len(routes) == 0 {
+		routes = stat.routes
+	}
+	if text, missing := missingRouteMessage(stat, routes); missing {
+		return tb.sendMsg(msgReq.Chat.ID, text)
+	}
+	for _, route := range routes {
+		router.SetRouteRaw(route, raw)
+	}
+	return tb.sendMsg(msgReq.Chat.ID, stat.t("routes.set_selected", rawModeName(raw, stat.language())))
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const redactedSecret = "***"
+
+// secretPatterns match strings which look like tokens of supported platforms, they are hidden even before
+// the bot knows they are tokens.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)oauth:[a-z0-9]+`),                                           // twitch chat token
+	regexp.MustCompile(`(?i)\b[a-z0-9]{30}\b`),                                          // twitch token without prefix
+	regexp.MustCompile(`(?i)\b[a-f0-9]{32,}\b`),                                         // vk token
+	regexp.MustCompile(`\bsy[td]_[A-Za-z0-9_]+`),                                        // matrix access token
+	regexp.MustCompile(`\b[A-Za-z0-9_-]{16,}\.[A-Za-z0-9_-]{16,}\.[A-Za-z0-9_-]{8,}\b`), // JWT
+	regexp.MustCompile(`\b\d{6,}:[A-Za-z0-9_-]{30,}\b`),                                 // telegram bot token
+	regexp.MustCompile(`(?i)\btoken=[^&\s]+`),                                           // token of webhook address
+}
+
+// redactor hides secrets in logs. Known secrets are hidden exactly, other secrets are recognized by patterns.
+type redactor struct {
+	mu      sync.RWMutex
+	secrets map[string]struct{}
+}
+
+func newRedactor() *redactor {
+	return &redactor{secrets: make(map[string]struct{})}
+}
+
+// Add registers secret. Too short secrets are ignored, because hiding them would break unrelated text.
+func (r *redactor) Add(secret string) {
+	if len(secret) < 8 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.secrets[secret] = struct{}{}
+}
+
+func (r *redactor) Redact(text string) string {
+	r.mu.RLock()
+	secrets := make([]string, 0, len(r.secrets))
+	for secret := range r.secrets {
+		secrets = append(secrets, secret)
+	}
+	r.mu.RUnlock()
+
+	// Longer secrets go first, so a secret containing another one is hidden whole.
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	for _, secret := range secrets {
+		text = strings.ReplaceAll(text, secret, redactedSecret)
+	}
+	for _, pattern := range secretPatterns {
+		text = pattern.ReplaceAllString(text, redactedSecret)
+	}
+	return text
+}
+
+// Writer returns writer which hides secrets before writing to w. It is used as output of loggers.
+func (r *redactor) Writer(w io.Writer) io.Writer {
+	return redactingWriter{redactor: r, w: w}
+}
+
+type redactingWriter struct {
+	redactor *redactor
+	w        io.Writer
+}
+
+func (rw redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(rw.w, rw.redactor.Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// maskSecret shows only edges of secret, so user can recognize it.
+func maskSecret(secret string) string {
+	if len(secret) < 12 {
+		return redactedSecret
+	}
+	return secret[:4] + "…" + secret[len(secret)-4:]
+}
@@ -0,0 +1,308 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/MrMamka/combchats/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// dialogue holds named sessions of one telegram chat. Messages which are not session commands go to the current session.
+type dialogue struct {
+	// mu guards sessions, which are changed by commands and counted by running sessions.
+	mu       sync.Mutex
+	sessions []*status
+	current  *status
+	// created counts sessions ever created in the chat, it gives default names.
+	created int
+	// lang is the language of the chat, empty until it is taken from the first user or set by /language.
+	lang i18n.Language
+}
+
+func newDialogue() *dialogue {
+	d := new(dialogue)
+	d.current = d.add("")
+	return d
+}
+
+// add creates session. Empty name is replaced by the next number.
+func (d *dialogue) add(name string) *status {
+	d.created++
+	if name == "" {
+		name = strconv.Itoa(d.created)
+		for d.find(name) != nil {
+			d.created++
+			name = strconv.Itoa(d.created)
+		}
+	}
+	stat := &status{stage: NotWorkingStage, dialogue: d}
+	stat.setName(name)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sessions = append(d.sessions, stat)
+	return stat
+}
+
+func (d *dialogue) find(name string) *status {
+	for _, stat := range d.sessions {
+		if strings.EqualFold(stat.sessionName(), name) {
+			return stat
+		}
+	}
+	return nil
+}
+
+func (d *dialogue) remove(removed *status) {
+	d.mu.Lock()
+	for i, stat := range d.sessions {
+		if stat == removed {
+			d.sessions = append(d.sessions[:i], d.sessions[i+1:]...)
+			break
+		}
+	}
+	d.mu.Unlock()
+
+	if d.current == removed {
+		if len(d.sessions) == 0 {
+			d.current = d.add("")
+		} else {
+			d.current = d.sessions[len(d.sessions)-1]
+		}
+	}
+}
+
+// byWizard returns session which shows the wizard message.
+func (d *dialogue) byWizard(messageID int) *status {
+	for _, stat := range d.sessions {
+		if stat.wizardMessageID == messageID {
+			return stat
+		}
+	}
+	return nil
+}
+
+func (s *status) sessionName() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.name
+}
+
+func (s *status) setName(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+}
+
+// sessionLabel returns prefix of messages shown by the session. Sessions are labeled only if the chat has several of them.
+func (s *status) sessionLabel() string {
+	if s.dialogue == nil || s.dialogue.count() < 2 {
+		return ""
+	}
+	return fmt.Sprintf("[%s] ", s.sessionName())
+}
+
+func (d *dialogue) language() i18n.Language {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.lang == "" {
+		return i18n.Default
+	}
+	return d.lang
+}
+
+func (d *dialogue) setLanguage(lang i18n.Language) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lang = lang
+}
+
+// t returns text of key in the language of the chat.
+func (d *dialogue) t(key string, args ...interface{}) string {
+	return d.language().T(key, args...)
+}
+
+func (s *status) language() i18n.Language {
+	if s.dialogue == nil {
+		return i18n.Default
+	}
+	return s.dialogue.language()
+}
+
+// t returns text of key in the language of the chat.
+func (s *status) t(key string, args ...interface{}) string {
+	return s.language().T(key, args...)
+}
+
+func (d *dialogue) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.sessions)
+}
+
+func (s *status) currentStage() Stage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stage
+}
+
+func (s *status) setStage(stage Stage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stage = stage
+}
+
+// stageAndStop returns stage of the session together with the channel closed when the session is stopped.
+func (s *status) stageAndStop() (Stage, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stage, s.stop
+}
+
+// startSession moves the session to working stage and returns the channel closed when the session is stopped.
+// Chats must be started with this channel: /stop may come while they connect, and then they stop right away.
+func (s *status) startSession(stage Stage) <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stage = stage
+	return s.stop
+}
+
+// resetStop makes stop channel for the next run of the session.
+func (s *status) resetStop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop = make(chan struct{})
+	s.stopped = false
+}
+
+// stopSession stops chats of the running session. It is safe to call several times.
+func (s *status) stopSession() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopLocked()
+}
+
+func (s *status) stopLocked() {
+	if s.stop != nil && !s.stopped {
+		close(s.stop)
+		s.stopped = true
+	}
+}
+
+// runningLocked reports whether the session started with stop is still running.
+func (s *status) runningLocked(stop <-chan struct{}) bool {
+	return s.stop == stop && !s.stopped
+}
+
+// abortSession stops the session started with stop, whose chats failed to start. The session is left as is
+// if it was already stopped, it may be set up again by then.
+func (s *status) abortSession(stop <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.runningLocked(stop) {
+		s.stopLocked()
+		s.stage = NotWorkingStage
+	}
+}
+
+// stageNames are catalog keys of stage descriptions in the list of sessions.
+var stageNames = map[Stage]string{
+	NotWorkingStage:             "stage.not_working",
+	ChooseModeStage:             "stage.choose_mode",
+	PendingChatsStage:           "stage.pending_chats",
+	WorkingCombiningStage:       "stage.combining",
+	PendingForwardingChatsStage: "stage.pending_chats",
+	PendingRoutesStage:          "stage.pending_routes",
+	PendingTokensStage:          "stage.pending_tokens",
+	WorkingForwardingStage:      "stage.forwarding",
+}
+
+// sessionsHandler lists sessions of the chat: /sessions.
+func (tb *TelegramBot) sessionsHandler(msgReq *tgbotapi.Message, d *dialogue) error {
+	lines := make([]string, len(d.sessions))
+	for i, stat := range d.sessions {
+		mark := ""
+		if stat == d.current {
+			mark = d.t("sessions.current_mark")
+		}
+		lines[i] = fmt.Sprintf("%s%s: %s, %s", stat.sessionName(), mark, d.t(stageNames[stat.currentStage()]),
+			d.language().N("sessions.chats", len(stat.channels)))
+	}
+	return tb.sendMsg(msgReq.Chat.ID, d.t("sessions.list", strings.Join(lines, "\n")))
+}
+
+// newSessionHandler creates session and makes it current: /new [name].
+func (tb *TelegramBot) newSessionHandler(msgReq *tgbotapi.Message, d *dialogue) error {
+	name := strings.TrimSpace(msgReq.CommandArguments())
+	if strings.ContainsAny(name, " \n") {
+		return tb.sendMsg(msgReq.Chat.ID, d.t("sessions.one_word"))
+	}
+	if name != "" && d.find(name) != nil {
+		return tb.sendMsg(msgReq.Chat.ID, d.t("sessions.exists_switch", name, name))
+	}
+
+	stat := d.add(name)
+	d.current = stat
+
+	_ = tb.sendMsg(msgReq.Chat.ID, d.t("sessions.created", stat.sessionName()))
+	return tb.notWorkingHandler(msgReq, stat)
+}
+
+// switchSessionHandler makes session current: /switch *name*.
+func (tb *TelegramBot) switchSessionHandler(msgReq *tgbotapi.Message, d *dialogue) error {
+	stat, err := tb.sessionByArgs(msgReq, d)
+	if err != nil || stat == nil {
+		return err
+	}
+	d.current = stat
+	return tb.sendMsg(msgReq.Chat.ID, d.t("sessions.switched", stat.sessionName(), d.t(stageNames[stat.currentStage()])))
+}
+
+// renameSessionHandler renames current session: /rename *name*.
+func (tb *TelegramBot) renameSessionHandler(msgReq *tgbotapi.Message, d *dialogue) error {
+	name := strings.TrimSpace(msgReq.CommandArguments())
+	switch {
+	case name == "":
+		return tb.sendMsg(msgReq.Chat.ID, d.t("sessions.rename_usage"))
+	case strings.ContainsAny(name, " \n"):
+		return tb.sendMsg(msgReq.Chat.ID, d.t("sessions.one_word"))
+	case d.find(name) != nil && d.find(name) != d.current:
+		return tb.sendMsg(msgReq.Chat.ID, d.t("sessions.exists", name))
+	}
+
+	old := d.current.sessionName()
+	d.current.setName(name)
+	return tb.sendMsg(msgReq.Chat.ID, d.t("sessions.renamed", old, name))
+}
+
+// closeSessionHandler stops session and removes it: /close *name*.
+func (tb *TelegramBot) closeSessionHandler(msgReq *tgbotapi.Message, d *dialogue) error {
+	stat, err := tb.sessionByArgs(msgReq, d)
+	if err != nil || stat == nil {
+		return err
+	}
+
+	stat.stopSession()
+	tb.clearWizard(msgReq.Chat.ID, stat)
+	d.remove(stat)
+
+	return tb.sendMsg(msgReq.Chat.ID, d.t("sessions.closed",
+		stat.sessionName(), d.current.sessionName(), d.t(stageNames[d.current.currentStage()])))
+}
+
+// sessionByArgs finds session named in arguments of command. Returns nil session if user was told it is not found.
+func (tb *TelegramBot) sessionByArgs(msgReq *tgbotapi.Message, d *dialogue) (*status, error) {
+	name := strings.TrimSpace(msgReq.CommandArguments())
+	if name == "" {
+		return nil, tb.sendMsg(msgReq.Chat.ID, d.t("sessions.name_usage", msgReq.Command(), d.current.sessionName()))
+	}
+	stat := d.find(name)
+	if stat == nil {
+		return nil, tb.sendMsg(msgReq.Chat.ID, d.t("sessions.not_found", name))
+	}
+	return stat, nil
+}
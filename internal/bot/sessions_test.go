@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/MrMamka/combchats/internal/chat"
+)
+
+func isClosed(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestStopSessionWhileStarting(t *testing.T) {
+	stat := newDialogue().current
+	stat.resetStop()
+	stop := stat.startSession(WorkingCombiningStage)
+
+	// /stop comes before chats are started with the captured channel.
+	stat.stopSession()
+	stat.stopSession()
+	stat.setStage(NotWorkingStage)
+	if !isClosed(stop) {
+		t.Fatalf("stop channel of stopped session is not closed")
+	}
+
+	// The session is set up again, then starting chats of the old run fails.
+	stat.resetStop()
+	stat.setStage(ChooseModeStage)
+	stat.abortSession(stop)
+	if stage := stat.currentStage(); stage != ChooseModeStage {
+		t.Errorf("stage after abort of the old run = %v, want %v", stage, ChooseModeStage)
+	}
+	if _, newStop := stat.stageAndStop(); isClosed(newStop) {
+		t.Errorf("abort of the old run stopped the new one")
+	}
+}
+
+func TestAbortSession(t *testing.T) {
+	stat := newDialogue().current
+	stat.resetStop()
+	stop := stat.startSession(WorkingForwardingStage)
+
+	stat.abortSession(stop)
+	if !isClosed(stop) {
+		t.Errorf("stop channel of aborted session is not closed")
+	}
+	if stage := stat.currentStage(); stage != NotWorkingStage {
+		t.Errorf("stage of aborted session = %v, want %v", stage, NotWorkingStage)
+	}
+}
+
+func TestAttachRouterAfterStop(t *testing.T) {
+	stat := newDialogue().current
+	stat.resetStop()
+	stop := stat.startSession(WorkingForwardingStage)
+
+	// /stop comes while senders log in.
+	stat.stopSession()
+	if stat.attachRouter(&chat.Router{}, stop) {
+		t.Errorf("router is attached to stopped session")
+	}
+	if stat.currentRouter() != nil {
+		t.Errorf("stopped session has router")
+	}
+
+	stat.resetStop()
+	stop = stat.startSession(WorkingForwardingStage)
+	if !stat.attachRouter(&chat.Router{}, stop) || stat.currentRouter() == nil {
+		t.Errorf("router is not attached to running session")
+	}
+}
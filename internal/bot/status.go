@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// connectionStateNames are catalog keys of connection states.
+var connectionStateNames = map[chat.ConnectionState]string{
+	chat.Connecting:   "status.connecting",
+	chat.Connected:    "status.connected",
+	chat.Disconnected: "status.disconnected",
+}
+
+// statusHandler shows health of every chat of the running session and counters of forwarding routes.
+func (tb *TelegramBot) statusHandler(msgReq *tgbotapi.Message, stat *status) error {
+	forwarding := stat.currentStage() == WorkingForwardingStage
+	router, combined := stat.currentRouter(), stat.currentCombined()
+	switch {
+	case forwarding && router == nil:
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("forwarding.starting"))
+	case !forwarding && combined == nil:
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("combining.starting"))
+	}
+	health := func(channel chat.Channel) (chat.ChannelHealth, bool) {
+		if forwarding {
+			return router.Health(channel)
+		}
+		return combined.Health(channel)
+	}
+
+	lang := stat.language()
+	var builder strings.Builder
+	builder.WriteString(lang.T("status.uptime", formatDuration(time.Since(stat.started), lang)))
+	for i, channel := range stat.channels {
+		fmt.Fprintf(&builder, "\n%d. %s: ", i+1, channelTitle(channel))
+		h, ok := health(channel)
+		if !ok {
+			builder.WriteString(lang.T("status.receives_only"))
+			continue
+		}
+		builder.WriteString(channelHealthText(h, lang))
+	}
+
+	if forwarding {
+		builder.WriteString(lang.T("status.routes"))
+		for _, route := range stat.routes {
+			routeStats := router.RouteStats(route)
+			builder.WriteString(lang.T("status.route",
+				channelTitle(route.From), channelTitle(route.To), routeStats.Sent, routeStats.Failed, routeStats.Suppressed))
+		}
+	}
+
+	return tb.sendMsg(msgReq.Chat.ID, builder.String())
+}
+
+func channelHealthText(h chat.ChannelHealth, lang i18n.Language) string {
+	parts := []string{lang.T(connectionStateNames[h.State]), lang.N("status.messages", h.Messages)}
+	if !h.LastMessage.IsZero() {
+		parts = append(parts, lang.T("status.last_message", formatDuration(time.Since(h.LastMessage), lang)))
+	}
+	if h.Reconnects > 0 {
+		parts = append(parts, lang.N("status.reconnects", h.Reconnects))
+	}
+	if h.LastError != nil {
+		parts = append(parts, lang.T("status.last_error", h.LastError))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatDuration formats duration rounded to seconds, for example "1 h 5 min" or "42 s".
+func formatDuration(d time.Duration, lang i18n.Language) string {
+	d = d.Round(time.Second)
+	hours, minutes, seconds := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	switch {
+	case hours > 0:
+		return lang.T("duration.hours", hours, minutes)
+	case minutes > 0:
+		return lang.T("duration.minutes", minutes, seconds)
+	default:
+		return lang.T("duration.seconds", seconds)
+	}
+}
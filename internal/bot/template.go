@@ -0,0 +1,150 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func (stat *status) currentTemplate() chat.Template {
+	stat.mu.Lock()
+	defer stat.mu.Unlock()
+	return stat.template
+}
+
+func (stat *status) setTemplate(t chat.Template) {
+	stat.mu.Lock()
+	defer stat.mu.Unlock()
+	stat.template = t
+}
+
+// templatePreview formats sample message from the channel.
+func templatePreview(t chat.Template, source chat.Channel, lang i18n.Language) string {
+	return t.In(lang).Format(chat.Message{
+		Text:   lang.T("template.sample_text"),
+		Author: "viewer",
+		Time:   time.Now(),
+		Roles:  []chat.Role{chat.SubscriberRole},
+		Source: source,
+	})
+}
+
+func previewSource(stat *status) chat.Channel {
+	if len(stat.channels) == 0 {
+		return chat.Channel{}
+	}
+	return stat.channels[0]
+}
+
+// combiningTemplateHandler shows or changes template of messages in combined chat: /template [template].
+func (tb *TelegramBot) combiningTemplateHandler(msgReq *tgbotapi.Message, stat *status) error {
+	raw := strings.TrimSpace(msgReq.CommandArguments())
+	if raw == "" {
+		current := stat.currentTemplate()
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("template.current",
+			current, templatePreview(current, previewSource(stat), stat.language()), stat.t("help.template")))
+	}
+
+	t, err := chat.ParseTemplate(raw)
+	if err != nil {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("template.invalid", err, stat.t("help.template")))
+	}
+	stat.setTemplate(t)
+
+	return tb.sendMsg(msgReq.Chat.ID, stat.t("template.changed", templatePreview(t, previewSource(stat), stat.language())))
+}
+
+// forwardingTemplateHandler shows or changes templates of routes: /template [routes] [template].
+// Without routes template is set for all routes.
+func (tb *TelegramBot) forwardingTemplateHandler(msgReq *tgbotapi.Message, stat *status) error {
+	router := stat.currentRouter()
+	if router == nil {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("forwarding.starting"))
+	}
+	args := strings.TrimSpace(msgReq.CommandArguments())
+	if args == "" {
+		lines := make([]string, 0, len(stat.routes))
+		for _, route := range stat.routes {
+			t := router.Template(route)
+			lines = append(lines, stat.t("template.route",
+				channelTitle(route.From), channelTitle(route.To), t, templatePreview(t, route.From, stat.language())))
+		}
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("template.routes",
+			channelsList(stat.channels), strings.Join(lines, "\n"), stat.t("help.template")))
+	}
+
+	routes, raw := splitRoutesArgs(args, stat)
+	t, err := chat.ParseTemplate(raw)
+	if err != nil {
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("template.invalid", err, stat.t("help.template")))
+	}
+
+	if len(routes) == 0 {
+		router.SetTemplate(t)
+		return tb.sendMsg(msgReq.Chat.ID, stat.t("template.changed_all", templatePreview(t, previewSource(stat), stat.language())))
+	}
+
+	if text, missing := missingRouteMessage(stat, routes); missing {
+		return tb.sendMsg(msgReq.Chat.ID, text)
+	}
+	for _, route := range routes {
+		router.SetRouteTemplate(route, t)
+	}
+	return tb.sendMsg(msgReq.Chat.ID, stat.t("template.changed_routes", templatePreview(t, routes[0].From, stat.language())))
+}
+
+// parseRoutesPrefix parses comma separated routes in the first word of text and returns the rest of text.
+func parseRoutesPrefix(text string, channels []chat.Channel, lang i18n.Language) ([]chat.Route, string, error) {
+	first, rest, _ := strings.Cut(text, " ")
+	var routes []chat.Route
+	for _, input := range strings.Split(first, ",") {
+		parsed, err := parseRouteInput(input, channels, lang)
+		if err != nil {
+			return nil, "", err
+		}
+		routes = append(routes, parsed...)
+	}
+	return routes, strings.TrimSpace(rest), nil
+}
+
+func hasRoute(stat *status, route chat.Route) bool {
+	for _, existing := range stat.routes {
+		if existing == route {
+			return true
+		}
+	}
+	return false
+}
+
+// splitRoutesArgs parses arguments "[routes] value" of commands changing route settings.
+// First word is a list of routes only if it can be parsed as routes, otherwise the whole text is value.
+func splitRoutesArgs(args string, stat *status) ([]chat.Route, string) {
+	routes, rest, err := parseRoutesPrefix(args, stat.channels, stat.language())
+	if err != nil {
+		return nil, args
+	}
+	return routes, rest
+}
+
+// missingRouteMessage returns error message if some of routes is not forwarded.
+func missingRouteMessage(stat *status, routes []chat.Route) (string, bool) {
+	for _, route := range routes {
+		if !hasRoute(stat, route) {
+			return stat.t("routes.missing", channelTitle(route.From), channelTitle(route.To)), true
+		}
+	}
+	return "", false
+}
+
+// routesSummary lists all routes with their setting.
+func routesSummary(stat *status, describe func(route chat.Route) string) string {
+	lines := make([]string, 0, len(stat.routes))
+	for _, route := range stat.routes {
+		lines = append(lines, fmt.Sprintf("%s → %s: %s", channelTitle(route.From), channelTitle(route.To), describe(route)))
+	}
+	return strings.Join(lines, "\n")
+}
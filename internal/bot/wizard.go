@@ -0,0 +1,312 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/MrMamka/combchats/internal/chat"
+	"github.com/MrMamka/combchats/internal/i18n"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Callback data of wizard buttons.
+const (
+	modeCallback     = "mode:"
+	platformCallback = "platform:"
+	removeCallback   = "remove:"
+	routeCallback    = "route:"
+	allCallback      = "all"
+	doneCallback     = "done"
+	cancelCallback   = "cancel"
+)
+
+// platformOrder is the order of platforms on buttons and in lists.
+var platformOrder = []chat.ChannelType{
+	chat.TwitchChannelType,
+	chat.VkChannelType,
+	chat.TelegramChannelType,
+	chat.IRCChannelType,
+	chat.MatrixChannelType,
+	chat.WebhookChannelType,
+}
+
+const maxButtonNameLength = 20
+
+// buttonName shortens channel name to fit on button.
+func buttonName(channel chat.Channel) string {
+	if utf8.RuneCountInString(channel.Name) <= maxButtonNameLength {
+		return channel.Name
+	}
+	return string([]rune(channel.Name)[:maxButtonNameLength-1]) + "…"
+}
+
+func controlsRow(lang i18n.Language, buttons ...tgbotapi.InlineKeyboardButton) []tgbotapi.InlineKeyboardButton {
+	return append(buttons, tgbotapi.NewInlineKeyboardButtonData(lang.T("wizard.cancel_button"), cancelCallback))
+}
+
+func modeKeyboard(lang i18n.Language) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(lang.T("wizard.forwarding_button"), modeCallback+"forwarding"),
+			tgbotapi.NewInlineKeyboardButtonData(lang.T("wizard.combining_button"), modeCallback+"combining"),
+		),
+	)
+}
+
+func chatsWizardText(stat *status) string {
+	var builder strings.Builder
+	if len(stat.channels) == 0 {
+		builder.WriteString(stat.t("wizard.no_chats"))
+	} else {
+		builder.WriteString(stat.t("wizard.chats", channelsList(stat.channels)))
+	}
+
+	if stat.pendingPlatform != nil {
+		builder.WriteString(stat.t("wizard.platform_chosen", PlatformsTypes[*stat.pendingPlatform]))
+	} else {
+		builder.WriteString(stat.t("wizard.choose_platform"))
+	}
+	return builder.String()
+}
+
+func chatsKeyboard(stat *status) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, channel := range stat.channels {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			"❌ "+channelTitle(channel), removeCallback+strconv.Itoa(i))))
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+	for _, platform := range platformOrder {
+		title := PlatformsTypes[platform]
+		if stat.pendingPlatform != nil && *stat.pendingPlatform == platform {
+			title = "▶️ " + title
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(title, platformCallback+PlatformsTypes[platform]))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	rows = append(rows, controlsRow(stat.language(),
+		tgbotapi.NewInlineKeyboardButtonData(stat.t("wizard.done_button"), doneCallback)))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func routesWizardText(stat *status) string {
+	routes := stat.t("wizard.no_routes")
+	if len(stat.routes) > 0 {
+		lines := make([]string, len(stat.routes))
+		for i, route := range stat.routes {
+			lines[i] = fmt.Sprintf("%s → %s", channelTitle(route.From), channelTitle(route.To))
+		}
+		routes = "\n" + strings.Join(lines, "\n")
+	}
+	return stat.t("wizard.routes", channelsList(stat.channels), routes)
+}
+
+func routesKeyboard(stat *status) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, first := range stat.channels {
+		for j := i + 1; j < len(stat.channels); j++ {
+			second := stat.channels[j]
+			forward := chat.Route{From: first, To: second}
+			backward := chat.Route{From: second, To: first}
+
+			var row []tgbotapi.InlineKeyboardButton
+			button := func(title, input string, active bool) {
+				if active {
+					title = "✅ " + title
+				}
+				row = append(row, tgbotapi.NewInlineKeyboardButtonData(title, routeCallback+input))
+			}
+			canForward := second.Type != chat.WebhookChannelType
+			canBackward := first.Type != chat.WebhookChannelType
+			if canForward {
+				button(fmt.Sprintf("%s → %s", buttonName(first), buttonName(second)),
+					fmt.Sprintf("%d>%d", i+1, j+1), hasRoute(stat, forward))
+			}
+			if canForward && canBackward {
+				button(fmt.Sprintf("%s ↔ %s", buttonName(first), buttonName(second)),
+					fmt.Sprintf("%d<>%d", i+1, j+1), hasRoute(stat, forward) && hasRoute(stat, backward))
+			}
+			if canBackward {
+				button(fmt.Sprintf("%s ← %s", buttonName(first), buttonName(second)),
+					fmt.Sprintf("%d<%d", i+1, j+1), hasRoute(stat, backward))
+			}
+			if len(row) > 0 {
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	rows = append(rows, controlsRow(stat.language(),
+		tgbotapi.NewInlineKeyboardButtonData(stat.t("wizard.all_button"), allCallback),
+		tgbotapi.NewInlineKeyboardButtonData(stat.t("wizard.done_button"), doneCallback),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// sendWizard sends message with keyboard of the current stage. Keyboard of the previous wizard message is removed,
+// so only the last one can be pressed.
+func (tb *TelegramBot) sendWizard(chatID int64, stat *status, text string, keyboard tgbotapi.InlineKeyboardMarkup) error {
+	tb.clearWizard(chatID, stat)
+
+	msg := tgbotapi.NewMessage(chatID, systemHTML(text))
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.ReplyMarkup = keyboard
+	sent, err := tb.bot.Send(msg)
+	if err != nil {
+		return err
+	}
+	stat.wizardMessageID = sent.MessageID
+	return nil
+}
+
+// editWizard replaces text and keyboard of the last wizard message.
+func (tb *TelegramBot) editWizard(chatID int64, stat *status, text string, keyboard tgbotapi.InlineKeyboardMarkup) error {
+	if stat.wizardMessageID == 0 {
+		return tb.sendWizard(chatID, stat, text, keyboard)
+	}
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, stat.wizardMessageID, systemHTML(text), keyboard)
+	edit.ParseMode = tgbotapi.ModeHTML
+	_, err := tb.bot.Request(edit)
+	return err
+}
+
+// clearWizard removes keyboard from the last wizard message.
+func (tb *TelegramBot) clearWizard(chatID int64, stat *status) {
+	if stat.wizardMessageID == 0 {
+		return
+	}
+	empty := tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+	_, _ = tb.bot.Request(tgbotapi.NewEditMessageReplyMarkup(chatID, stat.wizardMessageID, empty))
+	stat.wizardMessageID = 0
+}
+
+func (tb *TelegramBot) handleCallback(query *tgbotapi.CallbackQuery) {
+	if query.Message == nil {
+		return
+	}
+	chatID := query.Message.Chat.ID
+	var stat *status
+	lang := i18n.Default
+	if d := tb.dialogues[chatID]; d != nil {
+		stat = d.byWizard(query.Message.MessageID)
+		lang = d.language()
+	}
+	if stat == nil {
+		_, _ = tb.bot.Request(tgbotapi.NewCallback(query.ID, lang.T("wizard.outdated")))
+		return
+	}
+	_, _ = tb.bot.Request(tgbotapi.NewCallback(query.ID, ""))
+
+	if query.Data == cancelCallback {
+		go func() {
+			tb.cancelWizard(query.Message, stat)
+			tb.saveDialogue(chatID, stat.dialogue)
+		}()
+		return
+	}
+
+	handler, ok := tb.callbackHandlers[stat.currentStage()]
+	if !ok {
+		return
+	}
+	go func() {
+		handler(query, stat)
+		tb.saveDialogue(chatID, stat.dialogue)
+	}()
+}
+
+func (tb *TelegramBot) cancelWizard(msg *tgbotapi.Message, stat *status) error {
+	tb.clearWizard(msg.Chat.ID, stat)
+	stat.setStage(NotWorkingStage)
+	_ = tb.sendMsg(msg.Chat.ID, stat.t("wizard.cancelled"))
+	return tb.notWorkingHandler(msg, stat)
+}
+
+func (tb *TelegramBot) chooseModeCallback(query *tgbotapi.CallbackQuery, stat *status) error {
+	mode, ok := strings.CutPrefix(query.Data, modeCallback)
+	if !ok {
+		return nil
+	}
+	return tb.chooseMode(query.Message.Chat.ID, stat, mode)
+}
+
+func (tb *TelegramBot) chatsCallback(query *tgbotapi.CallbackQuery, stat *status) error {
+	chatID := query.Message.Chat.ID
+	switch {
+	case query.Data == doneCallback:
+		if stat.currentStage() == PendingChatsStage {
+			return tb.finishCombiningChats(chatID, stat)
+		}
+		return tb.finishForwardingChats(chatID, stat)
+	case strings.HasPrefix(query.Data, platformCallback):
+		platform, ok := Platforms[strings.TrimPrefix(query.Data, platformCallback)]
+		if !ok {
+			return nil
+		}
+		stat.pendingPlatform = &platform
+	case strings.HasPrefix(query.Data, removeCallback):
+		i, err := strconv.Atoi(strings.TrimPrefix(query.Data, removeCallback))
+		if err != nil || i < 0 || i >= len(stat.channels) {
+			return nil
+		}
+		stat.channels = append(stat.channels[:i], stat.channels[i+1:]...)
+	default:
+		return nil
+	}
+	return tb.editWizard(chatID, stat, chatsWizardText(stat), chatsKeyboard(stat))
+}
+
+func (tb *TelegramBot) routesCallback(query *tgbotapi.CallbackQuery, stat *status) error {
+	chatID := query.Message.Chat.ID
+	switch {
+	case query.Data == doneCallback:
+		return tb.finishRoutes(chatID, stat)
+	case query.Data == allCallback:
+		addAllRoutes(stat)
+		return tb.finishRoutes(chatID, stat)
+	case strings.HasPrefix(query.Data, routeCallback):
+		routes, err := parseRouteInput(strings.TrimPrefix(query.Data, routeCallback), stat.channels, stat.language())
+		if err != nil {
+			return nil
+		}
+		toggleRoutes(stat, routes)
+	default:
+		return nil
+	}
+	return tb.editWizard(chatID, stat, routesWizardText(stat), routesKeyboard(stat))
+}
+
+// toggleRoutes removes routes if all of them are set, otherwise adds missing ones.
+func toggleRoutes(stat *status, routes []chat.Route) {
+	all := true
+	for _, route := range routes {
+		all = all && hasRoute(stat, route)
+	}
+
+	for _, route := range routes {
+		if all {
+			removeRoute(stat, route)
+		} else {
+			addRoute(stat, route)
+		}
+	}
+}
+
+func removeRoute(stat *status, route chat.Route) {
+	for i, existing := range stat.routes {
+		if existing == route {
+			stat.routes = append(stat.routes[:i], stat.routes[i+1:]...)
+			return
+		}
+	}
+}
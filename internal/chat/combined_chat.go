@@ -1,14 +1,48 @@
 package chat
 
 import (
-	"fmt"
+	"errors"
+	"sync"
 	"time"
 )
 
 type Message struct {
+	// ID of the message on its platform, if the platform has one.
+	ID     string
 	Text   string
 	Author string
-	Time   time.Time
+	// AuthorURL is the page of the author's channel or profile, empty if the platform has none.
+	AuthorURL string
+	Time      time.Time
+	Event     Event
+	Roles     []Role
+	Emotes    []Emote
+	// Source is the channel where message was read. It is set by CombinedChat and Router.
+	Source Channel
+	// Platform is an optional label of the message source, set by sources which gather messages from several places.
+	Platform string
+	// ReplyTo is the ID of the message this message replies to, ReplyAuthor is its author.
+	ReplyTo     string
+	ReplyAuthor string
+}
+
+type Role string
+
+const (
+	BroadcasterRole Role = "broadcaster"
+	ModeratorRole   Role = "moderator"
+	VIPRole         Role = "vip"
+	SubscriberRole  Role = "subscriber"
+)
+
+// HasRole reports whether author of the message has role.
+func (m Message) HasRole(role Role) bool {
+	for _, r := range m.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
 
 type Chat interface {
@@ -16,20 +50,146 @@ type Chat interface {
 	Stop()
 }
 
+// stopSignal is closed when a chat is stopped. CombinedChat doesn't read a chat which is being stopped,
+// so callbacks of clients pass messages with send, which doesn't block the client after that.
+type stopSignal struct {
+	once sync.Once
+	done chan struct{}
+}
+
+func newStopSignal() *stopSignal {
+	return &stopSignal{done: make(chan struct{})}
+}
+
+func (s *stopSignal) signal() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+func (s *stopSignal) stopped() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// send passes msg to output or drops it if the chat is stopped.
+func (s *stopSignal) send(output chan<- Message, msg Message) {
+	select {
+	case output <- msg:
+	case <-s.done:
+	}
+}
+
 type CombinedChat struct {
-	chats []Chat
+	mu       sync.Mutex
+	attached []*attachedChat
+	filter   *Filter
+	// output and stop are set by Start, chats attached later are started immediately.
+	output  chan Message
+	stop    <-chan struct{}
+	started bool
+	stopped bool
 }
 
+// attachedChat is a chat of CombinedChat, which can be detached without stopping others.
+type attachedChat struct {
+	chat    Chat
+	channel Channel
+	detach  chan struct{}
+}
+
+var (
+	ErrChannelAlreadyAdded = errors.New("channel is already added")
+	ErrChannelNotFound     = errors.New("channel is not found")
+	ErrChatStopped         = errors.New("chat is stopped")
+)
+
 type ChannelType int
 
 const (
 	TwitchChannelType ChannelType = iota
 	VkChannelType
+	TelegramChannelType
+	IRCChannelType
+	MatrixChannelType
+	WebhookChannelType
 )
 
+var channelTypeNames = map[ChannelType]string{
+	TwitchChannelType:   "Twitch",
+	VkChannelType:       "Vk",
+	TelegramChannelType: "Telegram",
+	IRCChannelType:      "IRC",
+	MatrixChannelType:   "Matrix",
+	WebhookChannelType:  "Webhook",
+}
+
+func (ct ChannelType) String() string {
+	if name, ok := channelTypeNames[ct]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
 type Channel struct {
 	Type ChannelType
 	Name string
+	// Secret authenticates messages of the channel, it is the token of webhook channels. It is never shown
+	// and is not saved with the channel, so it must be kept separately.
+	Secret string `json:"-"`
+}
+
+func (c Channel) String() string {
+	return c.Type.String() + " " + c.Name
+}
+
+var ErrUndefinedChannelType = errors.New("undefined channel type")
+
+// newChat creates chat reading channel.
+func newChat(channel Channel) (Chat, error) {
+	switch channel.Type {
+	case TwitchChannelType:
+		return NewTwitchChat(channel.Name), nil
+	case VkChannelType:
+		return NewVkChat(channel.Name), nil
+	case TelegramChannelType:
+		return NewTelegramChat(channel.Name), nil
+	case IRCChannelType:
+		return NewIRCChat(channel.Name)
+	case MatrixChannelType:
+		return NewMatrixChat(channel.Name)
+	case WebhookChannelType:
+		return NewWebhookChat(channel.Name, channel.Secret)
+	default:
+		return nil, ErrUndefinedChannelType
+	}
+}
+
+// CheckChannel reports why chat of the channel can't be created, so the channel is rejected when it's entered.
+// Existence of the channel is not checked.
+func CheckChannel(channel Channel) error {
+	var err error
+	switch channel.Type {
+	case IRCChannelType:
+		_, err = parseIRCAddress(channel.Name)
+	case MatrixChannelType:
+		if Matrix.AccessToken == "" {
+			return ErrMatrixNotConfigured
+		}
+		_, _, err = parseMatrixAddress(channel.Name)
+	case WebhookChannelType:
+		if !Webhooks.Started() {
+			return ErrWebhooksDisabled
+		}
+		if channel.Name == "" || channel.Secret == "" {
+			return ErrWebhookAddress
+		}
+	}
+	return err
 }
 
 // TODO: сообщать пользователю, если пользователя не существует
@@ -37,33 +197,146 @@ func NewCombinedChat(channels []Channel) (*CombinedChat, error) {
 	result := new(CombinedChat)
 
 	for _, channel := range channels {
-		switch {
-		case channel.Type == TwitchChannelType:
-			result.chats = append(result.chats, NewTwitchChat(channel.Name))
-		case channel.Type == VkChannelType:
-			result.chats = append(result.chats, NewVkChat(channel.Name))
-		default:
-			return nil, fmt.Errorf("undefined channel type") // TODO: just ignore?
+		if err := result.Add(channel); err != nil {
+			return nil, err // TODO: just ignore?
 		}
 	}
 	return result, nil
 }
 
-func (cc *CombinedChat) Start(stop <-chan struct{}) <-chan Message {
-	resultChan := make(chan Message)
+// SetFilter sets filter of messages. It must be called before Start, but rules of filter can be changed later.
+func (cc *CombinedChat) SetFilter(filter *Filter) {
+	cc.filter = filter
+}
 
-	go func() {
-		for _, chat := range cc.chats {
-			go func(chat Chat) {
-				chat.Start(resultChan)
-			}(chat)
+// Add attaches chat of the channel. If CombinedChat is already started, the chat starts immediately.
+func (cc *CombinedChat) Add(channel Channel) error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.stopped {
+		return ErrChatStopped
+	}
+	if cc.indexOf(channel) >= 0 {
+		return ErrChannelAlreadyAdded
+	}
+
+	chat, err := openChat(channel)
+	if err != nil {
+		return err
+	}
+	attached := &attachedChat{chat: chat, channel: channel, detach: make(chan struct{})}
+	cc.attached = append(cc.attached, attached)
+	if cc.started {
+		cc.run(attached)
+	}
+	return nil
+}
+
+// Remove stops and detaches chat of the channel, other chats keep working.
+func (cc *CombinedChat) Remove(channel Channel) error {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if cc.stopped {
+		return ErrChatStopped
+	}
+	i := cc.indexOf(channel)
+	if i < 0 {
+		return ErrChannelNotFound
+	}
+
+	attached := cc.attached[i]
+	cc.attached = append(cc.attached[:i], cc.attached[i+1:]...)
+	close(attached.detach)
+	if cc.started {
+		attached.chat.Stop()
+	}
+	return nil
+}
+
+// Channels returns channels of attached chats in order of adding.
+func (cc *CombinedChat) Channels() []Channel {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	channels := make([]Channel, len(cc.attached))
+	for i, attached := range cc.attached {
+		channels[i] = attached.channel
+	}
+	return channels
+}
+
+// Health returns health of the chat of the channel. Returns false if channel is not attached.
+func (cc *CombinedChat) Health(channel Channel) (ChannelHealth, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	i := cc.indexOf(channel)
+	if i < 0 {
+		return ChannelHealth{}, false
+	}
+	return chatHealth(cc.attached[i].chat), true
+}
+
+func (cc *CombinedChat) indexOf(channel Channel) int {
+	for i, attached := range cc.attached {
+		if attached.channel == channel {
+			return i
 		}
+	}
+	return -1
+}
 
+func (cc *CombinedChat) Start(stop <-chan struct{}) <-chan Message {
+	cc.mu.Lock()
+	cc.output = make(chan Message)
+	cc.stop = stop
+	cc.started = true
+	for _, attached := range cc.attached {
+		cc.run(attached)
+	}
+	cc.mu.Unlock()
+
+	go func() {
 		<-stop
-		for _, chat := range cc.chats {
-			chat.Stop()
+
+		cc.mu.Lock()
+		defer cc.mu.Unlock()
+		cc.stopped = true
+		for _, attached := range cc.attached {
+			attached.chat.Stop()
 		}
 	}()
 
-	return resultChan
+	return cc.output
+}
+
+// run starts the chat and passes its messages to output until CombinedChat is stopped or the chat is detached.
+func (cc *CombinedChat) run(attached *attachedChat) {
+	input := make(chan Message)
+	attached.chat.Start(input)
+	go func() {
+		for {
+			select {
+			case msg := <-input:
+				countReceived(attached.chat)
+				msg.Source = attached.channel
+				if !cc.filter.Allow(msg) {
+					continue
+				}
+				select {
+				case cc.output <- msg:
+				case <-attached.detach:
+					return
+				case <-cc.stop:
+					return
+				}
+			case <-attached.detach:
+				return
+			case <-cc.stop:
+				return
+			}
+		}
+	}()
 }
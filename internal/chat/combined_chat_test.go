@@ -0,0 +1,175 @@
+package chat
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// combinedStep is an action on CombinedChat: "start", "stop", "add", "remove" or "write" of text to the chat of channel.
+type combinedStep struct {
+	action  string
+	channel Channel
+	text    string
+	err     error
+}
+
+func TestCombinedChatAttach(t *testing.T) {
+	tests := []struct {
+		name     string
+		channels []Channel
+		steps    []combinedStep
+		want     []Channel
+	}{
+		{
+			name:     "add after start",
+			channels: []Channel{testTwitch},
+			steps: []combinedStep{
+				{action: "start"},
+				{action: "add", channel: testVk},
+				{action: "write", channel: testVk, text: "hi"},
+				{action: "write", channel: testTwitch, text: "hey"},
+			},
+			want: []Channel{testTwitch, testVk},
+		},
+		{
+			name:     "remove after start",
+			channels: []Channel{testTwitch, testVk},
+			steps: []combinedStep{
+				{action: "start"},
+				{action: "remove", channel: testVk},
+				{action: "write", channel: testTwitch, text: "hey"},
+			},
+			want: []Channel{testTwitch},
+		},
+		{
+			name:     "add and remove before start",
+			channels: []Channel{testTwitch},
+			steps: []combinedStep{
+				{action: "add", channel: testVk},
+				{action: "remove", channel: testTwitch},
+				{action: "start"},
+				{action: "write", channel: testVk, text: "hi"},
+			},
+			want: []Channel{testVk},
+		},
+		{
+			name:     "removed channel is added again",
+			channels: []Channel{testTwitch, testVk},
+			steps: []combinedStep{
+				{action: "start"},
+				{action: "remove", channel: testVk},
+				{action: "add", channel: testVk},
+				{action: "write", channel: testVk, text: "hi"},
+			},
+			want: []Channel{testTwitch, testVk},
+		},
+		{
+			name:     "duplicate and unknown channels",
+			channels: []Channel{testTwitch},
+			steps: []combinedStep{
+				{action: "start"},
+				{action: "add", channel: testTwitch, err: ErrChannelAlreadyAdded},
+				{action: "remove", channel: testVk, err: ErrChannelNotFound},
+			},
+			want: []Channel{testTwitch},
+		},
+		{
+			name:     "stopped chat",
+			channels: []Channel{testTwitch},
+			steps: []combinedStep{
+				{action: "start"},
+				{action: "stop"},
+				{action: "add", channel: testVk, err: ErrChatStopped},
+				{action: "remove", channel: testTwitch, err: ErrChatStopped},
+			},
+			want: []Channel{testTwitch},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakes := useFakePlatforms(t)
+			combined, err := NewCombinedChat(tt.channels)
+			if err != nil {
+				t.Fatalf("NewCombinedChat() error = %v", err)
+			}
+			stop := make(chan struct{})
+			stopped := false
+			defer func() {
+				if !stopped {
+					close(stop)
+				}
+			}()
+
+			var output <-chan Message
+			for _, step := range tt.steps {
+				switch step.action {
+				case "start":
+					output = combined.Start(stop)
+				case "stop":
+					close(stop)
+					stopped = true
+					waitStopped(t, fakes.chat(testTwitch))
+				case "add":
+					if err := combined.Add(step.channel); !errors.Is(err, step.err) {
+						t.Fatalf("Add(%v) error = %v, want %v", step.channel, err, step.err)
+					}
+				case "remove":
+					removed := fakes.chat(step.channel)
+					if err := combined.Remove(step.channel); !errors.Is(err, step.err) {
+						t.Fatalf("Remove(%v) error = %v, want %v", step.channel, err, step.err)
+					}
+					if step.err == nil && output != nil {
+						waitStopped(t, removed)
+					}
+				case "write":
+					fakes.chat(step.channel).write(t, Message{Author: "alice", Text: step.text})
+					select {
+					case msg := <-output:
+						if msg.Text != step.text || msg.Source != step.channel {
+							t.Fatalf("got %q from %v, want %q from %v", msg.Text, msg.Source, step.text, step.channel)
+						}
+					case <-time.After(time.Second):
+						t.Fatalf("message %q from %v is not combined", step.text, step.channel)
+					}
+				}
+			}
+
+			if got := combined.Channels(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Channels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func waitStopped(t *testing.T, fc *fakeChat) {
+	t.Helper()
+	select {
+	case <-fc.stop:
+	case <-time.After(time.Second):
+		t.Fatalf("chat is not stopped")
+	}
+}
+
+func TestStopSignalSend(t *testing.T) {
+	output := make(chan Message)
+	stop := newStopSignal()
+
+	sent := make(chan struct{})
+	go func() {
+		stop.send(output, Message{Text: "late"})
+		close(sent)
+	}()
+	stop.signal()
+	stop.signal()
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("send is blocked after the chat is stopped")
+	}
+	if !stop.stopped() {
+		t.Error("stopped() = false after signal")
+	}
+}
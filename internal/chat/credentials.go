@@ -0,0 +1,132 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MrMamka/combchats/pkg/matrix"
+	"github.com/MrMamka/combchats/pkg/twitchapi"
+	vk "github.com/MrMamka/combchats/pkg/vkplaylive"
+)
+
+// twitchChatScope is the scope needed to send chat messages with twitch token.
+const twitchChatScope = "chat:edit"
+
+var (
+	ErrInvalidToken = errors.New("token is invalid or expired")
+	ErrMissingScope = errors.New("token doesn't have required scope")
+	ErrNoSenderName = errors.New("sender name is required")
+	ErrNoToken      = errors.New("token is required")
+	// ErrNotVerified means that the platform answered in unexpected format, the account may still be valid.
+	ErrNotVerified = errors.New("account can't be verified")
+)
+
+// AccountMismatchError is returned when sender name differs from the account of token.
+type AccountMismatchError struct {
+	Name    string
+	Account string
+}
+
+func (e *AccountMismatchError) Error() string {
+	return fmt.Sprintf("sender name %s doesn't match account of token %s", e.Name, e.Account)
+}
+
+// CredentialsSpec describes what is needed to send messages to a platform.
+type CredentialsSpec struct {
+	// Token is required, unless the platform sends on behalf of the bot itself.
+	Token bool
+	// Name is required. If it is not, name is detected from token.
+	Name bool
+	// OptionalToken is a password which can be omitted, for example of IRC nick without registration.
+	OptionalToken bool
+}
+
+var credentialsSpecs = map[ChannelType]CredentialsSpec{
+	TwitchChannelType:   {Token: true},
+	VkChannelType:       {Token: true},
+	TelegramChannelType: {},
+	IRCChannelType:      {Name: true, OptionalToken: true},
+	MatrixChannelType:   {Token: true},
+}
+
+// Credentials returns what is needed to send messages to the platform. Returns false if sending is not supported.
+func Credentials(channelType ChannelType) (CredentialsSpec, bool) {
+	spec, ok := credentialsSpecs[channelType]
+	return spec, ok
+}
+
+// VerifyReciever checks credentials of reciever on its platform without sending anything.
+// Returns reciever with the sender name of the token account. If the answer of the platform can't be read,
+// reciever is returned as entered with ErrNotVerified and can still be used.
+func VerifyReciever(reciever Reciever) (Reciever, error) {
+	spec, ok := Credentials(reciever.Type)
+	if !ok {
+		return reciever, ErrSendNotSupported
+	}
+	if spec.Token && reciever.AuthToken == "" {
+		return reciever, ErrNoToken
+	}
+	if spec.Name && reciever.SenderName == "" {
+		return reciever, ErrNoSenderName
+	}
+
+	var name string
+	var err error
+	switch reciever.Type {
+	case TwitchChannelType:
+		name, err = verifyTwitchToken(reciever.AuthToken)
+	case VkChannelType:
+		name, err = verifyVkToken(reciever.AuthToken)
+	case MatrixChannelType:
+		name, err = verifyMatrixToken(reciever.Name, reciever.AuthToken)
+	default:
+		return reciever, nil
+	}
+	if err != nil {
+		return reciever, err
+	}
+
+	if reciever.SenderName != "" && !strings.EqualFold(strings.TrimPrefix(reciever.SenderName, "@"), name) {
+		return reciever, &AccountMismatchError{Name: reciever.SenderName, Account: name}
+	}
+	reciever.SenderName = name
+	return reciever, nil
+}
+
+func verifyTwitchToken(token string) (string, error) {
+	validated, err := twitchapi.NewClient(token).Validate()
+	if errors.Is(err, twitchapi.ErrUnauthorized) {
+		return "", ErrInvalidToken
+	}
+	if err != nil {
+		return "", err
+	}
+	if !validated.HasScope(twitchChatScope) {
+		return "", fmt.Errorf("%w %s", ErrMissingScope, twitchChatScope)
+	}
+	return validated.Login, nil
+}
+
+func verifyVkToken(token string) (string, error) {
+	user, err := vk.NewClient(token).CurrentUser()
+	if errors.Is(err, vk.ErrUnauthorized) {
+		return "", ErrInvalidToken
+	}
+	if errors.Is(err, vk.ErrUnknownResponse) {
+		return "", fmt.Errorf("%w: %v", ErrNotVerified, err)
+	}
+	return user.Name, err
+}
+
+func verifyMatrixToken(channelName, token string) (string, error) {
+	homeserver, _, err := parseMatrixAddress(channelName)
+	if err != nil {
+		return "", err
+	}
+	userID, err := matrix.NewClient(homeserver, token).WhoAmI()
+	if errors.Is(err, matrix.ErrUnauthorized) {
+		return "", ErrInvalidToken
+	}
+	return matrixDisplayName(userID), err
+}
@@ -0,0 +1,116 @@
+package chat
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	relayCacheCapacity = 1000
+	relayCacheTTL      = 5 * time.Minute
+)
+
+// EchoStats counts messages which were recognized as copies written by our senders and not forwarded.
+type EchoStats struct {
+	// ByAuthor is the number of messages written by sender accounts but not found among relayed texts.
+	ByAuthor int64
+	// ByContent is the number of relayed texts which came back from destinations.
+	ByContent int64
+}
+
+type echoCounters struct {
+	byAuthor  int64
+	byContent int64
+}
+
+func (ec *echoCounters) stats() EchoStats {
+	return EchoStats{
+		ByAuthor:  atomic.LoadInt64(&ec.byAuthor),
+		ByContent: atomic.LoadInt64(&ec.byContent),
+	}
+}
+
+type relayKey struct {
+	channel Channel
+	text    string
+}
+
+type relayEntry struct {
+	key relayKey
+	// origin is the message which text was made from.
+	origin  messageKey
+	expires time.Time
+}
+
+// relayCache is a bounded record of texts recently sent to channels. Entries expire after ttl,
+// and the oldest entries are evicted when capacity is reached. It is safe for concurrent use.
+type relayCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[relayKey]*list.Element
+	// order holds entries from the oldest to the newest.
+	order *list.List
+}
+
+func newRelayCache(capacity int, ttl time.Duration) *relayCache {
+	return &relayCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[relayKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// normalizeEchoText makes text comparable after platforms trim or collapse whitespace.
+func normalizeEchoText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Add records that text made from origin message was sent to channel.
+func (rc *relayCache) Add(channel Channel, text string, origin messageKey) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	now := time.Now()
+	rc.evictExpired(now)
+
+	key := relayKey{channel: channel, text: normalizeEchoText(text)}
+	if elem, ok := rc.entries[key]; ok {
+		rc.order.Remove(elem)
+	}
+	rc.entries[key] = rc.order.PushBack(&relayEntry{key: key, origin: origin, expires: now.Add(rc.ttl)})
+
+	for rc.order.Len() > rc.capacity {
+		rc.remove(rc.order.Front())
+	}
+}
+
+// Pop removes record about text sent to channel and returns its origin message.
+func (rc *relayCache) Pop(channel Channel, text string) (messageKey, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.evictExpired(time.Now())
+
+	elem, ok := rc.entries[relayKey{channel: channel, text: normalizeEchoText(text)}]
+	if !ok {
+		return messageKey{}, false
+	}
+	rc.remove(elem)
+	return elem.Value.(*relayEntry).origin, true
+}
+
+func (rc *relayCache) evictExpired(now time.Time) {
+	for elem := rc.order.Front(); elem != nil && elem.Value.(*relayEntry).expires.Before(now); elem = rc.order.Front() {
+		rc.remove(elem)
+	}
+}
+
+func (rc *relayCache) remove(elem *list.Element) {
+	rc.order.Remove(elem)
+	delete(rc.entries, elem.Value.(*relayEntry).key)
+}
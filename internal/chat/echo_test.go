@@ -0,0 +1,56 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRelayCache(t *testing.T) {
+	origin := messageKey{channel: testTwitch, id: "1"}
+
+	tests := []struct {
+		name     string
+		capacity int
+		ttl      time.Duration
+		added    []string
+		pop      string
+		want     bool
+	}{
+		{name: "relayed text", capacity: 10, ttl: time.Minute, added: []string{"alice: hi"}, pop: "alice: hi", want: true},
+		{name: "whitespace is collapsed", capacity: 10, ttl: time.Minute, added: []string{"alice:  hi "}, pop: " alice: hi", want: true},
+		{name: "other text", capacity: 10, ttl: time.Minute, added: []string{"alice: hi"}, pop: "alice: hello"},
+		{name: "expired text", capacity: 10, ttl: -time.Second, added: []string{"alice: hi"}, pop: "alice: hi"},
+		{name: "evicted text", capacity: 2, ttl: time.Minute, added: []string{"alice: hi", "bob: hey", "carol: yo"}, pop: "alice: hi"},
+		{name: "newest text is kept", capacity: 2, ttl: time.Minute, added: []string{"alice: hi", "bob: hey", "carol: yo"}, pop: "carol: yo", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newRelayCache(tt.capacity, tt.ttl)
+			for _, text := range tt.added {
+				cache.Add(testVk, text, origin)
+			}
+			got, ok := cache.Pop(testVk, tt.pop)
+			if ok != tt.want {
+				t.Fatalf("Pop(%q) = %v, want %v", tt.pop, ok, tt.want)
+			}
+			if ok && got != origin {
+				t.Errorf("Pop(%q) origin = %v, want %v", tt.pop, got, origin)
+			}
+		})
+	}
+}
+
+func TestRelayCachePopsOnce(t *testing.T) {
+	cache := newRelayCache(10, time.Minute)
+	cache.Add(testVk, "alice: hi", messageKey{channel: testTwitch, id: "1"})
+
+	if _, ok := cache.Pop(testTelegram, "alice: hi"); ok {
+		t.Errorf("text sent to %v is popped from %v", testVk, testTelegram)
+	}
+	if _, ok := cache.Pop(testVk, "alice: hi"); !ok {
+		t.Fatalf("text is not popped")
+	}
+	if _, ok := cache.Pop(testVk, "alice: hi"); ok {
+		t.Errorf("text is popped twice")
+	}
+}
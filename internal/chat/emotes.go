@@ -0,0 +1,219 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Emote is a span of message text which is an emote of the source platform.
+type Emote struct {
+	Name string
+	// Start and End are byte offsets of the emote in message text.
+	Start int
+	End   int
+}
+
+// EmoteMapping describes one emote on different platforms. Emoji is used on platforms without own name.
+type EmoteMapping struct {
+	Names map[ChannelType]string
+	Emoji string
+}
+
+func (em EmoteMapping) String() string {
+	parts := make([]string, 0, len(em.Names)+1)
+	for _, channelType := range sortedChannelTypes(em.Names) {
+		parts = append(parts, fmt.Sprintf("%s=%s", strings.ToLower(channelType.String()), em.Names[channelType]))
+	}
+	if em.Emoji != "" {
+		parts = append(parts, "emoji="+em.Emoji)
+	}
+	return strings.Join(parts, " ")
+}
+
+func sortedChannelTypes(names map[ChannelType]string) []ChannelType {
+	types := make([]ChannelType, 0, len(names))
+	for channelType := range names {
+		types = append(types, channelType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// ParseEmoteMapping parses mapping in format "twitch=Kappa vk=kappa emoji=😏".
+func ParseEmoteMapping(text string) (EmoteMapping, error) {
+	mapping := EmoteMapping{Names: make(map[ChannelType]string)}
+	for _, field := range strings.Fields(text) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || value == "" {
+			return EmoteMapping{}, fmt.Errorf("expected platform=name, got %q", field)
+		}
+		if strings.EqualFold(key, "emoji") {
+			mapping.Emoji = value
+			continue
+		}
+
+		found := false
+		for channelType, name := range channelTypeNames {
+			if strings.EqualFold(key, name) {
+				mapping.Names[channelType] = value
+				found = true
+			}
+		}
+		if !found {
+			return EmoteMapping{}, fmt.Errorf("unknown platform %q", key)
+		}
+	}
+
+	if len(mapping.Names) == 0 {
+		return EmoteMapping{}, fmt.Errorf("mapping must contain at least one platform name")
+	}
+	return mapping, nil
+}
+
+// EmoteTable translates emotes between platforms. It is safe for concurrent use and can be edited while used.
+type EmoteTable struct {
+	mu       sync.RWMutex
+	mappings []EmoteMapping
+}
+
+var defaultEmoteMappings = []EmoteMapping{
+	{Names: map[ChannelType]string{TwitchChannelType: "Kappa"}, Emoji: "😏"},
+	{Names: map[ChannelType]string{TwitchChannelType: "PogChamp"}, Emoji: "😮"},
+	{Names: map[ChannelType]string{TwitchChannelType: "LUL"}, Emoji: "😂"},
+	{Names: map[ChannelType]string{TwitchChannelType: "BibleThump"}, Emoji: "😢"},
+	{Names: map[ChannelType]string{TwitchChannelType: "ResidentSleeper"}, Emoji: "😴"},
+	{Names: map[ChannelType]string{TwitchChannelType: "NotLikeThis"}, Emoji: "😱"},
+	{Names: map[ChannelType]string{TwitchChannelType: "HeyGuys"}, Emoji: "👋"},
+	{Names: map[ChannelType]string{TwitchChannelType: "SeemsGood"}, Emoji: "👍"},
+	{Names: map[ChannelType]string{TwitchChannelType: "DansGame"}, Emoji: "🤢"},
+	{Names: map[ChannelType]string{TwitchChannelType: "SwiftRage"}, Emoji: "😡"},
+	{Names: map[ChannelType]string{TwitchChannelType: "CoolCat"}, Emoji: "😺"},
+	{Names: map[ChannelType]string{TwitchChannelType: "<3"}, Emoji: "❤️"},
+}
+
+func NewEmoteTable(mappings []EmoteMapping) *EmoteTable {
+	et := &EmoteTable{}
+	for _, mapping := range mappings {
+		et.Set(mapping)
+	}
+	return et
+}
+
+// DefaultEmotes returns new table with Twitch global emotes mapped to emoji.
+func DefaultEmotes() *EmoteTable {
+	return NewEmoteTable(defaultEmoteMappings)
+}
+
+// Set adds mapping. Mappings sharing a platform name with it are replaced.
+func (et *EmoteTable) Set(mapping EmoteMapping) {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	names := make(map[ChannelType]string, len(mapping.Names))
+	for channelType, name := range mapping.Names {
+		names[channelType] = name
+	}
+	mapping.Names = names
+
+	kept := et.mappings[:0]
+	for _, existing := range et.mappings {
+		if !sharesName(existing, mapping) {
+			kept = append(kept, existing)
+		}
+	}
+	et.mappings = append(kept, mapping)
+}
+
+func sharesName(first, second EmoteMapping) bool {
+	for channelType, name := range first.Names {
+		if second.Names[channelType] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove removes mappings containing name on any platform. Returns false if there are no such mappings.
+func (et *EmoteTable) Remove(name string) bool {
+	et.mu.Lock()
+	defer et.mu.Unlock()
+
+	removed := false
+	kept := et.mappings[:0]
+	for _, mapping := range et.mappings {
+		if mapping.hasName(name) {
+			removed = true
+			continue
+		}
+		kept = append(kept, mapping)
+	}
+	et.mappings = kept
+	return removed
+}
+
+func (em EmoteMapping) hasName(name string) bool {
+	for _, mappingName := range em.Names {
+		if mappingName == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (et *EmoteTable) Mappings() []EmoteMapping {
+	et.mu.RLock()
+	defer et.mu.RUnlock()
+	return append([]EmoteMapping(nil), et.mappings...)
+}
+
+func (et *EmoteTable) lookup(from ChannelType, name string) (EmoteMapping, bool) {
+	for _, mapping := range et.mappings {
+		if mapping.Names[from] == name {
+			return mapping, true
+		}
+	}
+	return EmoteMapping{}, false
+}
+
+// Translate returns text of the message with emotes replaced by emotes of the destination platform.
+// If the destination has no such emote, emoji is used. Unknown emotes are left as is.
+func (et *EmoteTable) Translate(msg Message, to ChannelType) string {
+	if et == nil || len(msg.Emotes) == 0 || msg.Source.Type == to {
+		return msg.Text
+	}
+
+	emotes := append([]Emote(nil), msg.Emotes...)
+	sort.Slice(emotes, func(i, j int) bool { return emotes[i].Start < emotes[j].Start })
+
+	et.mu.RLock()
+	defer et.mu.RUnlock()
+
+	var builder strings.Builder
+	last := 0
+	for _, emote := range emotes {
+		if emote.Start < last || emote.End > len(msg.Text) || emote.Start > emote.End {
+			continue
+		}
+		mapping, ok := et.lookup(msg.Source.Type, emote.Name)
+		if !ok {
+			continue
+		}
+
+		replacement := mapping.Names[to]
+		if replacement == "" {
+			replacement = mapping.Emoji
+		}
+		if replacement == "" {
+			continue
+		}
+
+		builder.WriteString(msg.Text[last:emote.Start])
+		builder.WriteString(replacement)
+		last = emote.End
+	}
+	builder.WriteString(msg.Text[last:])
+
+	return builder.String()
+}
@@ -0,0 +1,113 @@
+package chat
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MrMamka/combchats/internal/i18n"
+)
+
+type EventType int
+
+const (
+	// MessageEvent is an ordinary chat message.
+	MessageEvent EventType = iota
+	// CheerEvent is a chat message with bits.
+	CheerEvent
+	AnnouncementEvent
+	SubEvent
+	ResubEvent
+	SubGiftEvent
+	// MassSubGiftEvent is a gift of several subs to random viewers.
+	MassSubGiftEvent
+	RaidEvent
+	// BanEvent is a ban of the target user, or timeout if Duration is not zero.
+	BanEvent
+	// ClearChatEvent is removal of all messages in chat.
+	ClearChatEvent
+	// DeleteMessageEvent is removal of the single message with TargetID.
+	DeleteMessageEvent
+)
+
+// IsChatMessage reports whether event carries text written by a user and so can be forwarded as a message.
+func (et EventType) IsChatMessage() bool {
+	return et == MessageEvent || et == CheerEvent || et == AnnouncementEvent
+}
+
+// IsModeration reports whether event removes messages from chat.
+func (et EventType) IsModeration() bool {
+	return et == BanEvent || et == ClearChatEvent || et == DeleteMessageEvent
+}
+
+// Event describes what happened in chat. Zero value is an ordinary message.
+type Event struct {
+	Type EventType
+	// Tier of the subscription: 1000, 2000, 3000 or Prime.
+	Plan string
+	// Months is the cumulative number of subscription months.
+	Months int
+	// Count is the number of gifted subs.
+	Count int
+	// Recipient of the gifted sub.
+	Recipient string
+	// Viewers is the number of viewers in raid.
+	Viewers int
+	Bits    int
+	// TargetUser is the user whose messages were removed.
+	TargetUser string
+	// TargetID is the id of the removed message.
+	TargetID string
+	Duration time.Duration
+}
+
+func subPlanName(plan string) string {
+	switch plan {
+	case "Prime":
+		return "Prime"
+	case "2000":
+		return "Tier 2"
+	case "3000":
+		return "Tier 3"
+	default:
+		return "Tier 1"
+	}
+}
+
+// eventToText returns human readable description of event in lang. Text of the message is appended if present.
+func eventToText(msg Message, lang i18n.Language) string {
+	var text string
+	switch msg.Event.Type {
+	case CheerEvent:
+		text = lang.N("event.cheer", msg.Event.Bits, msg.Author)
+	case AnnouncementEvent:
+		text = lang.T("event.announcement", msg.Author)
+	case SubEvent:
+		text = lang.T("event.sub", msg.Author, subPlanName(msg.Event.Plan))
+	case ResubEvent:
+		text = lang.N("event.resub", msg.Event.Months, msg.Author, subPlanName(msg.Event.Plan))
+	case SubGiftEvent:
+		text = lang.T("event.sub_gift", msg.Author, msg.Event.Recipient, subPlanName(msg.Event.Plan))
+	case MassSubGiftEvent:
+		text = lang.N("event.mass_sub_gift", msg.Event.Count, msg.Author, subPlanName(msg.Event.Plan))
+	case RaidEvent:
+		text = lang.N("event.raid", msg.Event.Viewers, msg.Author)
+	case BanEvent:
+		if msg.Event.Duration > 0 {
+			text = lang.T("event.timeout", msg.Event.TargetUser, msg.Event.Duration)
+		} else {
+			text = lang.T("event.ban", msg.Event.TargetUser)
+		}
+	case ClearChatEvent:
+		text = lang.T("event.clear_chat")
+	case DeleteMessageEvent:
+		// Deleted text is never shown, even if the source reports it.
+		return lang.T("event.delete_message", msg.Event.TargetUser)
+	default:
+		return fmt.Sprintf("%s: %s", msg.Author, msg.Text)
+	}
+
+	if msg.Text != "" {
+		text += ": " + msg.Text
+	}
+	return text
+}
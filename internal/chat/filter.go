@@ -0,0 +1,247 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	AllowAuthorRule  = "allow-author"
+	DenyAuthorRule   = "deny-author"
+	IncludeRule      = "include"
+	ExcludeRule      = "exclude"
+	IgnorePrefixRule = "ignore-prefix"
+	MinLengthRule    = "min-length"
+	MaxLengthRule    = "max-length"
+	RequireRoleRule  = "require-role"
+	DenyRoleRule     = "deny-role"
+)
+
+var ErrEmptyRule = errors.New("rule is empty")
+
+// Rule decides whether message passes filter.
+type Rule interface {
+	Allow(msg Message) bool
+	// String returns rule in the format accepted by ParseRule.
+	String() string
+}
+
+// ParseRule parses rule in format "*kind* *arguments*", for example "deny-author nightbot streamelements".
+//
+// Kinds of rules:
+//
+//	allow-author names... - only messages of these authors pass
+//	deny-author names...  - messages of these authors are dropped
+//	include regexp        - only messages matching regexp pass
+//	exclude regexp        - messages matching regexp are dropped
+//	ignore-prefix prefixes... - messages starting with one of prefixes are dropped
+//	min-length n, max-length n - messages shorter or longer than n characters are dropped
+//	require-role roles... - only messages of authors with one of roles pass
+//	deny-role roles...    - messages of authors with one of roles are dropped
+func ParseRule(text string) (Rule, error) {
+	kind, args, _ := strings.Cut(strings.TrimSpace(text), " ")
+	args = strings.TrimSpace(args)
+	if kind == "" {
+		return nil, ErrEmptyRule
+	}
+	if args == "" {
+		return nil, fmt.Errorf("rule %s needs arguments", kind)
+	}
+
+	switch kind {
+	case AllowAuthorRule, DenyAuthorRule:
+		authors := make(map[string]struct{})
+		for _, author := range strings.Fields(args) {
+			authors[strings.ToLower(strings.TrimPrefix(author, "@"))] = struct{}{}
+		}
+		return authorRule{allow: kind == AllowAuthorRule, authors: authors}, nil
+	case IncludeRule, ExcludeRule:
+		re, err := regexp.Compile(args)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp: %w", err)
+		}
+		return regexpRule{include: kind == IncludeRule, re: re}, nil
+	case IgnorePrefixRule:
+		return prefixRule{prefixes: strings.Fields(args)}, nil
+	case MinLengthRule, MaxLengthRule:
+		length, err := strconv.Atoi(args)
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("invalid length %q", args)
+		}
+		return lengthRule{min: kind == MinLengthRule, length: length}, nil
+	case RequireRoleRule, DenyRoleRule:
+		var roles []Role
+		for _, role := range strings.Fields(args) {
+			if _, ok := roleBadges[Role(role)]; !ok {
+				return nil, fmt.Errorf("unknown role %q", role)
+			}
+			roles = append(roles, Role(role))
+		}
+		return roleRule{require: kind == RequireRoleRule, roles: roles}, nil
+	default:
+		return nil, fmt.Errorf("unknown rule %q", kind)
+	}
+}
+
+type authorRule struct {
+	allow   bool
+	authors map[string]struct{}
+}
+
+func (ar authorRule) Allow(msg Message) bool {
+	_, listed := ar.authors[strings.ToLower(msg.Author)]
+	return listed == ar.allow
+}
+
+func (ar authorRule) String() string {
+	authors := make([]string, 0, len(ar.authors))
+	for author := range ar.authors {
+		authors = append(authors, author)
+	}
+	kind := DenyAuthorRule
+	if ar.allow {
+		kind = AllowAuthorRule
+	}
+	return kind + " " + strings.Join(authors, " ")
+}
+
+type regexpRule struct {
+	include bool
+	re      *regexp.Regexp
+}
+
+func (rr regexpRule) Allow(msg Message) bool {
+	return rr.re.MatchString(msg.Text) == rr.include
+}
+
+func (rr regexpRule) String() string {
+	if rr.include {
+		return IncludeRule + " " + rr.re.String()
+	}
+	return ExcludeRule + " " + rr.re.String()
+}
+
+type prefixRule struct {
+	prefixes []string
+}
+
+func (pr prefixRule) Allow(msg Message) bool {
+	text := strings.TrimSpace(msg.Text)
+	for _, prefix := range pr.prefixes {
+		if strings.HasPrefix(text, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func (pr prefixRule) String() string {
+	return IgnorePrefixRule + " " + strings.Join(pr.prefixes, " ")
+}
+
+type lengthRule struct {
+	min    bool
+	length int
+}
+
+func (lr lengthRule) Allow(msg Message) bool {
+	length := utf8.RuneCountInString(strings.TrimSpace(msg.Text))
+	if lr.min {
+		return length >= lr.length
+	}
+	return length <= lr.length
+}
+
+func (lr lengthRule) String() string {
+	if lr.min {
+		return fmt.Sprintf("%s %d", MinLengthRule, lr.length)
+	}
+	return fmt.Sprintf("%s %d", MaxLengthRule, lr.length)
+}
+
+type roleRule struct {
+	require bool
+	roles   []Role
+}
+
+func (rr roleRule) Allow(msg Message) bool {
+	for _, role := range rr.roles {
+		if msg.HasRole(role) {
+			return rr.require
+		}
+	}
+	return !rr.require
+}
+
+func (rr roleRule) String() string {
+	roles := make([]string, len(rr.roles))
+	for i, role := range rr.roles {
+		roles[i] = string(role)
+	}
+	kind := DenyRoleRule
+	if rr.require {
+		kind = RequireRoleRule
+	}
+	return kind + " " + strings.Join(roles, " ")
+}
+
+// Filter drops chat messages which don't pass all its rules. Notifications and moderation events always pass.
+//
+// Rules can be changed while messages are filtered.
+type Filter struct {
+	mu    sync.RWMutex
+	rules []Rule
+}
+
+func NewFilter() *Filter {
+	return &Filter{}
+}
+
+func (f *Filter) Add(rule Rule) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, rule)
+}
+
+// Remove removes rule by its index in Rules. Returns false if there is no such rule.
+func (f *Filter) Remove(index int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if index < 0 || index >= len(f.rules) {
+		return false
+	}
+	f.rules = append(f.rules[:index:index], f.rules[index+1:]...)
+	return true
+}
+
+func (f *Filter) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = nil
+}
+
+func (f *Filter) Rules() []Rule {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return append([]Rule(nil), f.rules...)
+}
+
+func (f *Filter) Allow(msg Message) bool {
+	if f == nil || !msg.Event.Type.IsChatMessage() {
+		return true
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, rule := range f.rules {
+		if !rule.Allow(msg) {
+			return false
+		}
+	}
+	return true
+}
@@ -3,76 +3,587 @@ package chat
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/MrMamka/combchats/internal/i18n"
+)
+
+const routerQueueSize = 100
+
+var (
+	ErrSendNotSupported = errors.New("messages can't be sent to this channel type")
+	ErrNoReciever       = errors.New("no reciever for route destination")
+	ErrSelfRoute        = errors.New("route from channel to itself")
 )
 
 type Reciever struct {
 	Channel
 	AuthToken  string
 	SenderName string
-	SentMsgs   map[string]struct{} // TODO: поменять на лру кэш
 }
 
 type Sender interface {
 	Send(string) error
+	// Limits returns restrictions of the platform on one message. Longer messages are split or truncated before Send.
+	Limits() Limits
+	// Sanitize makes text safe to send with the sender account: platform commands are neutralized
+	// and invisible characters are removed.
+	Sanitize(text string) string
 	Stop()
 }
 
-func Forward(from Channel, to Reciever, stop <-chan struct{}) error {
-	forwardChan := make(chan Message)
-	var fromChat Chat
-
-	switch from.Type {
+// newSender creates sender writing to the channel of reciever.
+func newSender(to Reciever) (Sender, error) {
+	switch to.Type {
 	case TwitchChannelType:
-		fromChat = NewTwitchChat(from.Name)
+		return NewTwitchSender(to.SenderName, to.Name, to.AuthToken)
 	case VkChannelType:
-		fromChat = NewVkChat(from.Name)
+		return NewVkSender(to.Name, to.AuthToken), nil
+	case TelegramChannelType:
+		return NewTelegramSender(to.Name), nil
+	case IRCChannelType:
+		return NewIRCSender(to.Name, to.SenderName, to.AuthToken)
+	case MatrixChannelType:
+		return NewMatrixSender(to.Name, to.AuthToken)
+	case WebhookChannelType:
+		return nil, ErrSendNotSupported
 	default:
-		return errors.New("undefined channel type")
+		return nil, ErrUndefinedChannelType
 	}
+}
 
-	var sender Sender
-	switch to.Type {
-	case TwitchChannelType:
-		sender = NewTwitchSender(to.SenderName, to.Name, to.AuthToken)
-	case VkChannelType:
-		sender = NewVkSender(to.Name, to.AuthToken)
-	default:
-		return errors.New("undefined channel type")
-	}
-
-	go func() {
-		fromChat.Start(forwardChan)
-	}()
-
-	go func() {
-		for {
-			var msg Message
-			select {
-			case msg = <-forwardChan:
-			case <-stop:
-				fromChat.Stop()
-				sender.Stop()
-				return
+// openChat and openSender create chats of sources and senders of destinations. Tests replace them with fakes.
+var (
+	openChat   = newChat
+	openSender = newSender
+)
+
+// Route is a directed forwarding of messages from one channel to another.
+type Route struct {
+	From Channel
+	To   Channel
+}
+
+// Router forwards messages between channels by the set of routes.
+//
+// Every source is read by one connection and its messages are fanned out to all destinations of its routes.
+// Relayed messages are tagged with their origin, so when a destination is also a source,
+// the copy written by our sender is recognized and forwarded only to channels not reached by the origin directly.
+// Other messages written by sender accounts (recognized by SenderName) are not forwarded at all.
+type Router struct {
+	recievers    map[Channel]Reciever
+	routes       map[Channel][]Channel
+	destinations []Channel
+	// identities holds lowercased names of sender accounts in every destination.
+	identities map[Channel]string
+
+	// relayed holds texts sent to destinations with the origin message of each text.
+	relayed *relayCache
+	// threads maps forwarded messages to their copies, so replies keep their context.
+	threads *threadCache
+	echoes  echoCounters
+	// counters of every route and chats of sources, filled by NewRouter and Start. Chats are guarded by mu,
+	// because health of them can be asked while Start is still creating them.
+	counters map[Route]*routeCounters
+	chats    map[Channel]Chat
+	filter   *Filter
+	emotes   *EmoteTable
+
+	// limits, sanitizers of destination senders and whether they send native replies, filled by Start.
+	limits     map[Channel]Limits
+	sanitizers map[Channel]func(string) string
+	threaded   map[Channel]bool
+
+	mu          sync.RWMutex
+	language    i18n.Language
+	template    Template
+	templates   map[Route]Template
+	policy      LengthPolicy
+	policies    map[Route]LengthPolicy
+	moderations map[Route]ModerationPolicy
+	raw         map[Route]bool
+}
+
+// NewRouter checks routes and creates router. Every route destination must have a reciever.
+func NewRouter(recievers []Reciever, routes []Route) (*Router, error) {
+	r := &Router{
+		recievers:   make(map[Channel]Reciever),
+		routes:      make(map[Channel][]Channel),
+		identities:  make(map[Channel]string),
+		relayed:     newRelayCache(relayCacheCapacity, relayCacheTTL),
+		threads:     newThreadCache(threadCacheCapacity, threadCacheTTL),
+		limits:      make(map[Channel]Limits),
+		sanitizers:  make(map[Channel]func(string) string),
+		threaded:    make(map[Channel]bool),
+		template:    DefaultTemplate,
+		templates:   make(map[Route]Template),
+		policies:    make(map[Route]LengthPolicy),
+		moderations: make(map[Route]ModerationPolicy),
+		raw:         make(map[Route]bool),
+		counters:    make(map[Route]*routeCounters),
+		chats:       make(map[Channel]Chat),
+	}
+
+	for _, reciever := range recievers {
+		r.recievers[reciever.Channel] = reciever
+	}
+
+	for _, route := range routes {
+		if route.From == route.To {
+			return nil, ErrSelfRoute
+		}
+		if _, ok := r.recievers[route.To]; !ok {
+			return nil, fmt.Errorf("%w %v", ErrNoReciever, route.To)
+		}
+		if r.hasRoute(route) {
+			continue
+		}
+		r.routes[route.From] = append(r.routes[route.From], route.To)
+		r.counters[route] = new(routeCounters)
+		if !r.isDestination(route.To) {
+			r.destinations = append(r.destinations, route.To)
+			if name := r.recievers[route.To].SenderName; name != "" {
+				r.identities[route.To] = strings.ToLower(name)
 			}
+		}
+	}
 
-			msgText := MessageToText(msg)
-			if to.SentMsgs != nil {
-				if _, ok := to.SentMsgs[msg.Text]; ok {
-					continue
-				}
+	return r, nil
+}
+
+func (r *Router) isDestination(channel Channel) bool {
+	for _, to := range r.destinations {
+		if to == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// EchoStats returns counters of suppressed echoes. It is safe to call while router works.
+func (r *Router) EchoStats() EchoStats {
+	return r.echoes.stats()
+}
+
+// SetFilter sets filter of forwarded messages. It must be called before Start, but rules of filter can be changed later.
+// Echoes of relayed messages are not filtered again.
+func (r *Router) SetFilter(filter *Filter) {
+	r.filter = filter
+}
+
+// SetEmotes sets table used to translate emotes for destinations. It must be called before Start,
+// but the table can be edited later.
+func (r *Router) SetEmotes(emotes *EmoteTable) {
+	r.emotes = emotes
+}
+
+// SetTemplate sets format of messages for all routes without own template. It can be called while router works.
+func (r *Router) SetTemplate(t Template) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.template = t
+}
+
+// SetRouteTemplate sets format of messages for the route. It can be called while router works.
+func (r *Router) SetRouteTemplate(route Route, t Template) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.templates[route] = t
+}
+
+// SetLanguage sets language of notifications in forwarded messages, e.g. of cheers. It can be called while router works.
+func (r *Router) SetLanguage(lang i18n.Language) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.language = lang
+}
+
+// Template returns format of messages for the route in the language of the router.
+func (r *Router) Template(route Route) Template {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if t, ok := r.templates[route]; ok {
+		return t.In(r.language)
+	}
+	return r.template.In(r.language)
+}
+
+// SetLengthPolicy sets what to do with long messages on all routes without own policy.
+// It can be called while router works.
+func (r *Router) SetLengthPolicy(policy LengthPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = policy
+}
+
+// SetRouteLengthPolicy sets what to do with long messages on the route. It can be called while router works.
+func (r *Router) SetRouteLengthPolicy(route Route, policy LengthPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policies[route] = policy
+}
 
-				to.SentMsgs[msgText] = struct{}{}
+// LengthPolicy returns what is done with long messages on the route.
+func (r *Router) LengthPolicy(route Route) LengthPolicy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if policy, ok := r.policies[route]; ok {
+		return policy
+	}
+	return r.policy
+}
+
+// SetRouteRaw turns off sanitization of messages on the route, so commands and invisible characters
+// are sent as is. It can be called while router works.
+func (r *Router) SetRouteRaw(route Route, raw bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.raw[route] = raw
+}
+
+// Raw reports whether messages on the route are sent without sanitization.
+func (r *Router) Raw(route Route) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.raw[route]
+}
+
+// RouterSettings are options of Router which can be changed while it works, in a form suitable for saving.
+type RouterSettings struct {
+	Template string
+	Policy   LengthPolicy
+	Routes   []RouteSettings
+}
+
+// RouteSettings are options set for one route. Empty Template and nil Policy mean options of all routes.
+type RouteSettings struct {
+	Route      Route
+	Template   string        `json:",omitempty"`
+	Policy     *LengthPolicy `json:",omitempty"`
+	Moderation ModerationPolicy
+	Raw        bool
+}
+
+// Settings returns current options of router.
+func (r *Router) Settings() RouterSettings {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	settings := RouterSettings{Template: r.template.String(), Policy: r.policy}
+	for from, destinations := range r.routes {
+		for _, to := range destinations {
+			route := Route{From: from, To: to}
+			routeSettings := RouteSettings{Route: route, Moderation: r.moderations[route], Raw: r.raw[route]}
+			if t, ok := r.templates[route]; ok {
+				routeSettings.Template = t.String()
 			}
+			if policy, ok := r.policies[route]; ok {
+				routeSettings.Policy = &policy
+			}
+			settings.Routes = append(settings.Routes, routeSettings)
+		}
+	}
+	return settings
+}
 
-			if err := sender.Send(msgText); err != nil {
-				fmt.Printf("error in sending message: %v\n", err)
+// ApplySettings sets options saved by Settings. Options of unknown routes are skipped.
+func (r *Router) ApplySettings(settings RouterSettings) error {
+	t, err := ParseTemplate(settings.Template)
+	if err != nil {
+		return err
+	}
+	r.SetTemplate(t)
+	r.SetLengthPolicy(settings.Policy)
+
+	for _, routeSettings := range settings.Routes {
+		route := routeSettings.Route
+		if !r.hasRoute(route) {
+			continue
+		}
+		if routeSettings.Template != "" {
+			t, err := ParseTemplate(routeSettings.Template)
+			if err != nil {
+				return err
 			}
+			r.SetRouteTemplate(route, t)
+		}
+		if routeSettings.Policy != nil {
+			r.SetRouteLengthPolicy(route, *routeSettings.Policy)
+		}
+		r.SetRouteModeration(route, routeSettings.Moderation)
+		r.SetRouteRaw(route, routeSettings.Raw)
+	}
+	return nil
+}
+
+func (r *Router) hasRoute(route Route) bool {
+	for _, to := range r.routes[route.From] {
+		if to == route.To {
+			return true
+		}
+	}
+	return false
+}
+
+// Start connects to all channels of routes and forwards messages until stop is closed.
+func (r *Router) Start(stop <-chan struct{}) error {
+	senders := make(map[Channel]Sender)
+	stopSenders := func() {
+		for _, sender := range senders {
+			sender.Stop()
+		}
+	}
+
+	for _, to := range r.destinations {
+		sender, err := openSender(r.recievers[to])
+		if err != nil {
+			stopSenders()
+			return fmt.Errorf("unable to create sender for %v: %w", to, err)
 		}
-	}()
+		senders[to] = sender
+		r.limits[to] = sender.Limits()
+		r.sanitizers[to] = sender.Sanitize
+		_, r.threaded[to] = sender.(ReplySender)
+	}
+
+	for from := range r.routes {
+		fromChat, err := openChat(from)
+		if err != nil {
+			stopSenders()
+			return fmt.Errorf("unable to create chat for %v: %w", from, err)
+		}
+		r.mu.Lock()
+		r.chats[from] = fromChat
+		r.mu.Unlock()
+	}
+
+	queues := make(map[Channel]chan outgoing)
+	for to, sender := range senders {
+		queue := make(chan outgoing, routerQueueSize)
+		queues[to] = queue
+		go r.send(to, sender, queue, stop)
+	}
+
+	for from, fromChat := range r.chats {
+		input := make(chan Message)
+		fromChat.Start(input)
+		go r.fanOut(from, fromChat, input, queues, stop)
+	}
 
 	return nil
 }
 
+// outgoing is a text queued to destination.
+type outgoing struct {
+	text string
+	// replyTo is the ID of the message in destination this text replies to.
+	replyTo string
+	// origin is the message which text was made from, from is the source it is forwarded from.
+	origin messageKey
+	from   Channel
+	// moderation is set instead of text for moderation actions.
+	moderation *moderation
+}
+
+func (r *Router) send(to Channel, sender Sender, queue <-chan outgoing, stop <-chan struct{}) {
+	for {
+		select {
+		case out := <-queue:
+			if out.moderation != nil {
+				r.moderate(to, sender, *out.moderation)
+				continue
+			}
+
+			var id string
+			var err error
+			switch sender := sender.(type) {
+			case ReplySender:
+				id, err = sender.SendReply(out.text, out.replyTo)
+			case TrackedSender:
+				id, err = sender.SendTracked(out.text)
+			default:
+				err = sender.Send(out.text)
+			}
+			counters := r.counters[Route{From: out.from, To: to}]
+			if err != nil {
+				atomic.AddInt64(&counters.failed, 1)
+				fmt.Printf("error in sending message: %v\n", err)
+				continue
+			}
+			atomic.AddInt64(&counters.sent, 1)
+			r.threads.AddCopy(out.origin, to, id)
+		case <-stop:
+			sender.Stop()
+			return
+		}
+	}
+}
+
+func (r *Router) fanOut(from Channel, fromChat Chat, input <-chan Message, queues map[Channel]chan outgoing, stop <-chan struct{}) {
+	for {
+		var msg Message
+		select {
+		case msg = <-input:
+		case <-stop:
+			fromChat.Stop()
+			return
+		}
+		countReceived(fromChat)
+
+		if msg.Event.Type.IsModeration() {
+			r.mirrorModeration(from, msg, queues)
+			continue
+		}
+		// Notifications are shown only in combined chats, they are not messages of users.
+		if !msg.Event.Type.IsChatMessage() {
+			continue
+		}
+
+		// Echo is the copy of a relayed message, it is forwarded further on behalf of its origin.
+		msg.Source = from
+		origin, isEcho := r.relayed.Pop(from, msg.Text)
+		if isEcho {
+			atomic.AddInt64(&r.echoes.byContent, 1)
+			r.threads.AddCopy(origin, from, msg.ID)
+		} else if r.isOwnMessage(from, msg) {
+			atomic.AddInt64(&r.echoes.byAuthor, 1)
+			r.suppress(from)
+			continue
+		} else if !r.filter.Allow(msg) {
+			r.suppress(from)
+			continue
+		} else {
+			origin = messageKey{channel: from, id: msg.ID}
+			r.threads.Add(origin, msg.Author)
+		}
+
+		var parent thread
+		hasParent := false
+		if !isEcho && msg.ReplyTo != "" {
+			parent, hasParent = r.threads.Lookup(messageKey{channel: from, id: msg.ReplyTo})
+		}
+
+		for _, to := range r.routes[from] {
+			route := Route{From: from, To: to}
+			raw := r.Raw(route)
+			msgText, replyTo := msg.Text, ""
+			if !isEcho {
+				translated := msg
+				translated.Text, replyTo = r.replyContext(to, msg, r.emotes.Translate(msg, to.Type), parent, hasParent)
+				if !raw {
+					translated = SanitizeMessage(translated)
+				}
+				msgText = r.Template(route).Format(translated)
+			}
+			for i, part := range FitMessage(msgText, r.limits[to], r.LengthPolicy(route)) {
+				if !raw {
+					part = r.sanitizers[to](part)
+				}
+				if !r.markRelayed(from, to, part, origin) {
+					atomic.AddInt64(&r.counters[route].suppressed, 1)
+					break
+				}
+				out := outgoing{text: part, origin: origin, from: from}
+				if i == 0 {
+					out.replyTo = replyTo
+				}
+				select {
+				case queues[to] <- out:
+				default:
+					atomic.AddInt64(&r.counters[route].failed, 1)
+					fmt.Printf("queue of %v is full, message dropped\n", to)
+				}
+			}
+		}
+	}
+}
+
+// suppress counts message which is not forwarded by any route from the source.
+func (r *Router) suppress(from Channel) {
+	for _, to := range r.routes[from] {
+		atomic.AddInt64(&r.counters[Route{From: from, To: to}].suppressed, 1)
+	}
+}
+
+// RouteStats returns counters of messages on the route. It is safe to call while router works.
+func (r *Router) RouteStats(route Route) RouteStats {
+	counters, ok := r.counters[route]
+	if !ok {
+		return RouteStats{}
+	}
+	return counters.stats()
+}
+
+// Health returns health of the chat reading the source. Returns false if channel is not a source of started router.
+func (r *Router) Health(channel Channel) (ChannelHealth, bool) {
+	r.mu.RLock()
+	fromChat, ok := r.chats[channel]
+	r.mu.RUnlock()
+	if !ok {
+		return ChannelHealth{}, false
+	}
+	return chatHealth(fromChat), true
+}
+
+// isOwnMessage reports whether message is written by our sender account in this channel.
+func (r *Router) isOwnMessage(from Channel, msg Message) bool {
+	return r.isOwnName(from, msg.Author)
+}
+
+func (r *Router) isOwnName(channel Channel, name string) bool {
+	identity, ok := r.identities[channel]
+	return ok && strings.ToLower(name) == identity
+}
+
+// replyContext keeps the context of reply in destination. If the parent message or its copy is in destination
+// and sender supports it, the text is sent as native reply to it, otherwise the author of parent is mentioned.
+// Returns text and ID of the message in destination to reply to.
+func (r *Router) replyContext(to Channel, msg Message, text string, parent thread, hasParent bool) (string, string) {
+	if msg.ReplyTo == "" {
+		return text, ""
+	}
+
+	author := msg.ReplyAuthor
+	if hasParent {
+		if id, ok := parent.idIn(to); ok && r.threaded[to] {
+			return stripMention(text, msg.ReplyAuthor), id
+		}
+		author = parent.author
+	} else if r.isOwnName(msg.Source, author) {
+		// Reply to a relayed message which is already forgotten, its real author is unknown.
+		return text, ""
+	}
+
+	if author == "" {
+		return text, ""
+	}
+	return "@" + author + ", " + stripMention(text, msg.ReplyAuthor), ""
+}
+
+// markRelayed tags text sent to the destination with its origin. Returns false if text must not be sent there:
+// the destination is the origin itself or the origin forwards there directly.
+func (r *Router) markRelayed(from, to Channel, msgText string, origin messageKey) bool {
+	if to == origin.channel {
+		return false
+	}
+	if origin.channel != from && r.hasRoute(Route{From: origin.channel, To: to}) {
+		return false
+	}
+	r.relayed.Add(to, msgText, origin)
+	return true
+}
+
+// Forward forwards messages from one channel to reciever. It is a router with a single route.
+func Forward(from Channel, to Reciever, stop <-chan struct{}) error {
+	router, err := NewRouter([]Reciever{to}, []Route{{From: from, To: to.Channel}})
+	if err != nil {
+		return err
+	}
+	return router.Start(stop)
+}
+
 func MessageToText(msg Message) string {
-	return fmt.Sprintf("%s: %s", msg.Author, msg.Text)
+	return DefaultTemplate.Format(msg)
 }
@@ -0,0 +1,393 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeChat is a chat of a source without platform, messages written to it are passed to the reader.
+type fakeChat struct {
+	messages chan Message
+	stop     chan struct{}
+	once     sync.Once
+}
+
+func newFakeChat() *fakeChat {
+	return &fakeChat{messages: make(chan Message), stop: make(chan struct{})}
+}
+
+func (fc *fakeChat) Start(output chan<- Message) {
+	go func() {
+		for {
+			select {
+			case msg := <-fc.messages:
+				select {
+				case output <- msg:
+				case <-fc.stop:
+					return
+				}
+			case <-fc.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (fc *fakeChat) Stop() {
+	fc.once.Do(func() { close(fc.stop) })
+}
+
+// write passes message to the reader of the chat.
+func (fc *fakeChat) write(t *testing.T, msg Message) {
+	t.Helper()
+	select {
+	case fc.messages <- msg:
+	case <-time.After(time.Second):
+		t.Fatalf("message %q is not read", msg.Text)
+	}
+}
+
+// fakeSender is a sender without platform, it keeps sent texts.
+type fakeSender struct {
+	sent chan string
+	stop chan struct{}
+	once sync.Once
+}
+
+func newFakeSender() *fakeSender {
+	return &fakeSender{sent: make(chan string, routerQueueSize), stop: make(chan struct{})}
+}
+
+func (fs *fakeSender) Send(text string) error {
+	fs.sent <- text
+	return nil
+}
+
+func (fs *fakeSender) Limits() Limits {
+	return Limits{}
+}
+
+func (fs *fakeSender) Sanitize(text string) string {
+	return text
+}
+
+func (fs *fakeSender) Stop() {
+	fs.once.Do(func() { close(fs.stop) })
+}
+
+// fakeReplySender is a sender with native replies. Replies are sent as "[reply to <id>] <text>",
+// IDs of sent messages are the channel name with the number of message.
+type fakeReplySender struct {
+	*fakeSender
+	channel Channel
+	count   int
+}
+
+func (fs *fakeReplySender) SendReply(text, replyTo string) (string, error) {
+	if replyTo != "" {
+		text = fmt.Sprintf("[reply to %s] %s", replyTo, text)
+	}
+	fs.count++
+	fs.sent <- text
+	return fmt.Sprintf("%s-%d", fs.channel.Name, fs.count), nil
+}
+
+// fakePlatforms replaces chats and senders of all channels with fakes until the end of the test.
+type fakePlatforms struct {
+	mu      sync.Mutex
+	chats   map[Channel]*fakeChat
+	senders map[Channel]*fakeSender
+	// threaded are destinations which senders send native replies.
+	threaded map[Channel]bool
+}
+
+func useFakePlatforms(t *testing.T) *fakePlatforms {
+	fakes := &fakePlatforms{
+		chats:    make(map[Channel]*fakeChat),
+		senders:  make(map[Channel]*fakeSender),
+		threaded: make(map[Channel]bool),
+	}
+	openChat = func(channel Channel) (Chat, error) {
+		fakes.mu.Lock()
+		defer fakes.mu.Unlock()
+		fakes.chats[channel] = newFakeChat()
+		return fakes.chats[channel], nil
+	}
+	openSender = func(to Reciever) (Sender, error) {
+		fakes.mu.Lock()
+		defer fakes.mu.Unlock()
+		fakes.senders[to.Channel] = newFakeSender()
+		if fakes.threaded[to.Channel] {
+			return &fakeReplySender{fakeSender: fakes.senders[to.Channel], channel: to.Channel}, nil
+		}
+		return fakes.senders[to.Channel], nil
+	}
+	t.Cleanup(func() {
+		openChat, openSender = newChat, newSender
+	})
+	return fakes
+}
+
+func (fp *fakePlatforms) chat(channel Channel) *fakeChat {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	return fp.chats[channel]
+}
+
+func (fp *fakePlatforms) sender(channel Channel) *fakeSender {
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	return fp.senders[channel]
+}
+
+// expectSent checks that sender sends the text next, or sends nothing if want is empty.
+func (fs *fakeSender) expectSent(t *testing.T, to Channel, want string) {
+	t.Helper()
+	if want == "" {
+		select {
+		case got := <-fs.sent:
+			t.Fatalf("%v got %q, want nothing", to, got)
+		case <-time.After(50 * time.Millisecond):
+		}
+		return
+	}
+	select {
+	case got := <-fs.sent:
+		if got != want {
+			t.Fatalf("%v got %q, want %q", to, got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("%v got nothing, want %q", to, want)
+	}
+}
+
+var (
+	testTwitch   = Channel{Type: TwitchChannelType, Name: "streamer"}
+	testVk       = Channel{Type: VkChannelType, Name: "streamer"}
+	testTelegram = Channel{Type: TelegramChannelType, Name: "@streamer"}
+)
+
+// routerStep writes message to the source and checks texts sent to destinations, other destinations get nothing.
+type routerStep struct {
+	from Channel
+	msg  Message
+	want map[Channel]string
+}
+
+func TestRouter(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []Route
+		// threaded are destinations with native replies.
+		threaded []Channel
+		// raw are routes without sanitization.
+		raw   []Route
+		steps []routerStep
+	}{
+		{
+			name:   "one route",
+			routes: []Route{{From: testTwitch, To: testVk}},
+			steps: []routerStep{
+				{from: testTwitch, msg: Message{Author: "alice", Text: "hi"}, want: map[Channel]string{testVk: "alice: hi"}},
+			},
+		},
+		{
+			name:   "fan out",
+			routes: []Route{{From: testTwitch, To: testVk}, {From: testTwitch, To: testTelegram}},
+			steps: []routerStep{
+				{from: testTwitch, msg: Message{Author: "alice", Text: "hi"}, want: map[Channel]string{testVk: "alice: hi", testTelegram: "alice: hi"}},
+			},
+		},
+		{
+			name:   "echo is not sent back",
+			routes: []Route{{From: testTwitch, To: testVk}, {From: testVk, To: testTwitch}},
+			steps: []routerStep{
+				{from: testTwitch, msg: Message{Author: "alice", Text: "hi"}, want: map[Channel]string{testVk: "alice: hi"}},
+				{from: testVk, msg: Message{Author: "bot", Text: "alice: hi"}},
+				{from: testVk, msg: Message{Author: "bob", Text: "hey"}, want: map[Channel]string{testTwitch: "bob: hey"}},
+			},
+		},
+		{
+			name:   "echo is forwarded on behalf of origin",
+			routes: []Route{{From: testTwitch, To: testVk}, {From: testVk, To: testTelegram}},
+			steps: []routerStep{
+				{from: testTwitch, msg: Message{Author: "alice", Text: "hi"}, want: map[Channel]string{testVk: "alice: hi"}},
+				{from: testVk, msg: Message{Author: "bot", Text: "alice: hi"}, want: map[Channel]string{testTelegram: "alice: hi"}},
+			},
+		},
+		{
+			name: "echo is not forwarded where origin forwards directly",
+			routes: []Route{
+				{From: testTwitch, To: testVk}, {From: testVk, To: testTelegram}, {From: testTwitch, To: testTelegram},
+			},
+			steps: []routerStep{
+				{from: testTwitch, msg: Message{Author: "alice", Text: "hi"}, want: map[Channel]string{testVk: "alice: hi", testTelegram: "alice: hi"}},
+				{from: testVk, msg: Message{Author: "bot", Text: "alice: hi"}},
+			},
+		},
+		{
+			name:   "messages of sender account are not forwarded",
+			routes: []Route{{From: testTwitch, To: testVk}, {From: testVk, To: testTwitch}},
+			steps: []routerStep{
+				{from: testTwitch, msg: Message{Author: "bot", Text: "forgotten relayed text"}},
+				{from: testTwitch, msg: Message{Author: "alice", Text: "hi"}, want: map[Channel]string{testVk: "alice: hi"}},
+			},
+		},
+		{
+			name:   "notifications are not forwarded",
+			routes: []Route{{From: testTwitch, To: testVk}},
+			steps: []routerStep{
+				{from: testTwitch, msg: Message{Author: "alice", Event: Event{Type: SubEvent}}},
+				{from: testTwitch, msg: Message{Author: "alice", Text: "hi"}, want: map[Channel]string{testVk: "alice: hi"}},
+			},
+		},
+		{
+			name:   "reply mentions author without native replies",
+			routes: []Route{{From: testTwitch, To: testVk}},
+			steps: []routerStep{
+				{from: testTwitch, msg: Message{ID: "1", Author: "alice", Text: "hi"}, want: map[Channel]string{testVk: "alice: hi"}},
+				{
+					from: testTwitch,
+					msg:  Message{ID: "2", Author: "bob", Text: "@alice yes", ReplyTo: "1", ReplyAuthor: "alice"},
+					want: map[Channel]string{testVk: "bob: @alice, yes"},
+				},
+			},
+		},
+		{
+			name:     "reply is native to the copy",
+			routes:   []Route{{From: testTwitch, To: testTelegram}},
+			threaded: []Channel{testTelegram},
+			steps: []routerStep{
+				{from: testTwitch, msg: Message{ID: "1", Author: "alice", Text: "hi"}, want: map[Channel]string{testTelegram: "alice: hi"}},
+				{
+					from: testTwitch,
+					msg:  Message{ID: "2", Author: "bob", Text: "@alice yes", ReplyTo: "1", ReplyAuthor: "alice"},
+					want: map[Channel]string{testTelegram: "[reply to @streamer-1] bob: yes"},
+				},
+			},
+		},
+		{
+			name:     "reply to the copy mentions author of original",
+			routes:   []Route{{From: testTwitch, To: testTelegram}, {From: testTelegram, To: testTwitch}},
+			threaded: []Channel{testTelegram},
+			steps: []routerStep{
+				{from: testTwitch, msg: Message{ID: "1", Author: "alice", Text: "hi"}, want: map[Channel]string{testTelegram: "alice: hi"}},
+				{
+					from: testTelegram,
+					msg:  Message{ID: "7", Author: "carol", Text: "hello", ReplyTo: "@streamer-1", ReplyAuthor: "Bot"},
+					want: map[Channel]string{testTwitch: "carol: @alice, hello"},
+				},
+			},
+		},
+		{
+			name:   "reply to forgotten copy doesn't mention sender account",
+			routes: []Route{{From: testTwitch, To: testVk}, {From: testVk, To: testTwitch}},
+			steps: []routerStep{
+				{
+					from: testVk,
+					msg:  Message{ID: "7", Author: "carol", Text: "hello", ReplyTo: "3", ReplyAuthor: "Bot"},
+					want: map[Channel]string{testTwitch: "carol: hello"},
+				},
+			},
+		},
+		{
+			name:   "author can't impersonate other messages",
+			routes: []Route{{From: testTwitch, To: testVk}},
+			steps: []routerStep{
+				{from: testTwitch, msg: Message{Author: "[Vk] bob:", Text: "hi"}, want: map[Channel]string{testVk: "(Vk) bob\ua789: hi"}},
+			},
+		},
+		{
+			name:   "raw route is not sanitized",
+			routes: []Route{{From: testTwitch, To: testVk}},
+			raw:    []Route{{From: testTwitch, To: testVk}},
+			steps: []routerStep{
+				{from: testTwitch, msg: Message{Author: "[Vk] bob:", Text: "hi"}, want: map[Channel]string{testVk: "[Vk] bob:: hi"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakes := useFakePlatforms(t)
+			for _, channel := range tt.threaded {
+				fakes.threaded[channel] = true
+			}
+			var recievers []Reciever
+			for _, route := range tt.routes {
+				recievers = append(recievers, Reciever{Channel: route.To, SenderName: "Bot"})
+			}
+			router, err := NewRouter(recievers, tt.routes)
+			if err != nil {
+				t.Fatalf("NewRouter() error = %v", err)
+			}
+			for _, route := range tt.raw {
+				router.SetRouteRaw(route, true)
+			}
+			stop := make(chan struct{})
+			defer close(stop)
+			if err := router.Start(stop); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+
+			for _, step := range tt.steps {
+				fakes.chat(step.from).write(t, step.msg)
+				for to, sender := range fakes.senders {
+					sender.expectSent(t, to, step.want[to])
+				}
+			}
+		})
+	}
+}
+
+func TestNewRouterErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		recievers []Reciever
+		routes    []Route
+		want      error
+	}{
+		{
+			name:      "self route",
+			recievers: []Reciever{{Channel: testTwitch}},
+			routes:    []Route{{From: testTwitch, To: testTwitch}},
+			want:      ErrSelfRoute,
+		},
+		{
+			name:      "no reciever",
+			recievers: []Reciever{{Channel: testVk}},
+			routes:    []Route{{From: testVk, To: testTwitch}},
+			want:      ErrNoReciever,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRouter(tt.recievers, tt.routes); !errors.Is(err, tt.want) {
+				t.Errorf("NewRouter() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterStop(t *testing.T) {
+	fakes := useFakePlatforms(t)
+	router, err := NewRouter([]Reciever{{Channel: testVk}}, []Route{{From: testTwitch, To: testVk}})
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+	stop := make(chan struct{})
+	if err := router.Start(stop); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	close(stop)
+
+	for name, stopped := range map[string]chan struct{}{"chat": fakes.chat(testTwitch).stop, "sender": fakes.sender(testVk).stop} {
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Errorf("%s is not stopped", name)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package chat
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type ConnectionState int
+
+const (
+	Connecting ConnectionState = iota
+	Connected
+	Disconnected
+)
+
+var connectionStateNames = map[ConnectionState]string{
+	Connecting:   "connecting",
+	Connected:    "connected",
+	Disconnected: "disconnected",
+}
+
+func (cs ConnectionState) String() string {
+	return connectionStateNames[cs]
+}
+
+// ChannelHealth describes connection of a chat and messages read from it.
+type ChannelHealth struct {
+	State       ConnectionState
+	Messages    int
+	LastMessage time.Time
+	Reconnects  int
+	LastError   error
+}
+
+// HealthReporter is implemented by chats which track their connection.
+type HealthReporter interface {
+	Health() ChannelHealth
+}
+
+// health is embedded into chats. The chat reports changes of connection, readers report received messages.
+type health struct {
+	mu           sync.Mutex
+	current      ChannelHealth
+	wasConnected bool
+}
+
+func (h *health) Health() ChannelHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.current
+}
+
+func (h *health) connected() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.current.State == Connected {
+		return
+	}
+	if h.wasConnected {
+		h.current.Reconnects++
+	}
+	h.wasConnected = true
+	h.current.State = Connected
+}
+
+// disconnected marks connection as lost. Error is nil if connection was closed on purpose.
+func (h *health) disconnected(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.current.State = Disconnected
+	if err != nil {
+		h.current.LastError = err
+	}
+}
+
+func (h *health) failed(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.current.LastError = err
+}
+
+func (h *health) received() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.current.Messages++
+	h.current.LastMessage = time.Now()
+}
+
+// countReceived counts message read from the chat if the chat tracks its health.
+func countReceived(chat Chat) {
+	if h, ok := chat.(interface{ received() }); ok {
+		h.received()
+	}
+}
+
+// chatHealth returns health of the chat. Chats which don't track connection are reported as connected.
+func chatHealth(chat Chat) ChannelHealth {
+	if reporter, ok := chat.(HealthReporter); ok {
+		return reporter.Health()
+	}
+	return ChannelHealth{State: Connected}
+}
+
+// RouteStats are counters of messages on a route of Router.
+type RouteStats struct {
+	Sent       int64
+	Failed     int64
+	Suppressed int64
+}
+
+type routeCounters struct {
+	sent       int64
+	failed     int64
+	suppressed int64
+}
+
+func (rc *routeCounters) stats() RouteStats {
+	return RouteStats{
+		Sent:       atomic.LoadInt64(&rc.sent),
+		Failed:     atomic.LoadInt64(&rc.failed),
+		Suppressed: atomic.LoadInt64(&rc.suppressed),
+	}
+}
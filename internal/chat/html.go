@@ -0,0 +1,114 @@
+package chat
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// htmlToken is a tag, an entity or a character of text in telegram HTML.
+type htmlToken struct {
+	text  string
+	units int // visible length in UTF-16 units, zero for tags
+	tag   bool
+}
+
+func (t htmlToken) isSpace() bool {
+	return t.text == " " || t.text == "\n"
+}
+
+func tokenizeHTML(text string) []htmlToken {
+	var tokens []htmlToken
+	for text != "" {
+		token := htmlToken{units: 1}
+		switch text[0] {
+		case '<':
+			if end := strings.IndexByte(text, '>'); end > 0 {
+				token = htmlToken{text: text[:end+1], tag: true}
+			}
+		case '&':
+			if end := strings.IndexByte(text, ';'); end > 0 {
+				token.text = text[:end+1]
+			}
+		}
+		if token.text == "" {
+			r, size := utf8.DecodeRuneInString(text)
+			token.text, token.units = text[:size], len(utf16.Encode([]rune{r}))
+		}
+		tokens = append(tokens, token)
+		text = text[len(token.text):]
+	}
+	return tokens
+}
+
+// openTags returns tags which are still open after tokens, starting with tags opened before them.
+func openTags(opened []string, tokens []htmlToken) []string {
+	open := append([]string(nil), opened...)
+	for _, token := range tokens {
+		switch {
+		case !token.tag:
+		case strings.HasPrefix(token.text, "</"):
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		default:
+			open = append(open, token.text)
+		}
+	}
+	return open
+}
+
+// closingTag returns closing tag for the opening one, for example "</a>" for `<a href="...">`.
+func closingTag(tag string) string {
+	name := strings.TrimSuffix(strings.TrimPrefix(tag, "<"), ">")
+	if i := strings.IndexByte(name, ' '); i >= 0 {
+		name = name[:i]
+	}
+	return "</" + name + ">"
+}
+
+// SplitHTML splits text in telegram HTML into parts not longer than limit. Length is counted like telegram does:
+// in UTF-16 units of visible text, so tags are not counted and an entity is one character. Parts are cut
+// on word boundaries when possible, tags open at the cut are closed and opened again in the next part.
+func SplitHTML(text string, limit int) []string {
+	tokens := tokenizeHTML(text)
+	var (
+		parts  []string
+		opened []string
+	)
+	for start := 0; start < len(tokens); {
+		end, length, space := start, 0, -1
+		for end < len(tokens) && (length+tokens[end].units <= limit || length == 0) {
+			if tokens[end].isSpace() {
+				space = end
+			}
+			length += tokens[end].units
+			end++
+		}
+		// Cut on the last space if it doesn't make the part too short.
+		if end < len(tokens) && space > start+(end-start)/2 {
+			end = space
+		}
+
+		var builder strings.Builder
+		builder.WriteString(strings.Join(opened, ""))
+		visible := false
+		for _, token := range tokens[start:end] {
+			builder.WriteString(token.text)
+			visible = visible || (!token.tag && !token.isSpace())
+		}
+		opened = openTags(opened, tokens[start:end])
+		for i := len(opened) - 1; i >= 0; i-- {
+			builder.WriteString(closingTag(opened[i]))
+		}
+		if visible {
+			parts = append(parts, builder.String())
+		}
+
+		start = end
+		for start < len(tokens) && tokens[start].isSpace() {
+			start++
+		}
+	}
+	return parts
+}
@@ -0,0 +1,62 @@
+package chat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{name: "short", text: "<b>hello</b>", limit: 10, want: []string{"<b>hello</b>"}},
+		{name: "words", text: "hello world again", limit: 11, want: []string{"hello world", "again"}},
+		{name: "no spaces", text: "abcdefghij", limit: 4, want: []string{"abcd", "efgh", "ij"}},
+		{name: "tags are reopened", text: "<b>aaaa bbbb</b>", limit: 5, want: []string{"<b>aaaa</b>", "<b>bbbb</b>"}},
+		{
+			name:  "link is reopened",
+			text:  `<a href="https://example.com">one two</a> three`,
+			limit: 5,
+			want:  []string{`<a href="https://example.com">one</a>`, `<a href="https://example.com">two</a>`, "three"},
+		},
+		{name: "nested tags", text: "<b><i>aa bb</i></b>", limit: 2, want: []string{"<b><i>aa</i></b>", "<b><i>bb</i></b>"}},
+		{name: "entity is one character", text: "&lt;&lt;&lt;", limit: 2, want: []string{"&lt;&lt;", "&lt;"}},
+		{name: "utf16 surrogate pairs", text: "🟣🟣🟣", limit: 4, want: []string{"🟣🟣", "🟣"}},
+		{name: "cyrillic", text: "привет мир", limit: 6, want: []string{"привет", "мир"}},
+		{name: "line breaks", text: "first\nsecond", limit: 7, want: []string{"first", "second"}},
+		{name: "empty", text: "", limit: 10, want: nil},
+		{name: "only spaces", text: "   ", limit: 2, want: nil},
+		{name: "only tags", text: "<b></b>", limit: 2, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitHTML(tt.text, tt.limit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitHTML(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitHTMLLimit(t *testing.T) {
+	text := "<b>" + strings.Repeat("слово &amp; 🟣 ", 1000) + "</b>"
+	parts := SplitHTML(text, TelegramMessageLimit)
+	if len(parts) < 2 {
+		t.Fatalf("SplitHTML returned %d parts, want several", len(parts))
+	}
+	for i, part := range parts {
+		length := 0
+		for _, token := range tokenizeHTML(part) {
+			length += token.units
+		}
+		if length > TelegramMessageLimit {
+			t.Errorf("part %d has length %d, limit is %d", i, length, TelegramMessageLimit)
+		}
+		if !strings.HasPrefix(part, "<b>") || !strings.HasSuffix(part, "</b>") {
+			t.Errorf("part %d is not wrapped in its tags: %q...%q", i, part[:10], part[len(part)-10:])
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MrMamka/combchats/pkg/irc"
+)
+
+const (
+	ircPort    = "6667"
+	ircTLSPort = "6697"
+	// ircLoginTimeout limits registration on server and joining the channel by NewIRCSender.
+	ircLoginTimeout = 30 * time.Second
+)
+
+// ircAddress is a parsed IRC channel name.
+//
+// Channel name format: [irc://|ircs://]host[:port]/channel[?auth=sasl|nickserv|pass].
+// Without scheme TLS is used. Auth option tells how sender password is used, sasl by default.
+type ircAddress struct {
+	server  string
+	tls     bool
+	channel string
+	auth    string
+}
+
+func parseIRCAddress(name string) (ircAddress, error) {
+	addr := ircAddress{tls: true, auth: "sasl"}
+
+	switch {
+	case strings.HasPrefix(name, "ircs://"):
+		name = strings.TrimPrefix(name, "ircs://")
+	case strings.HasPrefix(name, "irc://"):
+		name = strings.TrimPrefix(name, "irc://")
+		addr.tls = false
+	}
+
+	host, rest, ok := strings.Cut(name, "/")
+	if !ok || host == "" {
+		return ircAddress{}, fmt.Errorf("irc channel %q must be in format server/channel", name)
+	}
+
+	channel, options, _ := strings.Cut(rest, "?")
+	channel = strings.TrimLeft(channel, "#")
+	if channel == "" {
+		return ircAddress{}, fmt.Errorf("irc channel %q has no channel name", name)
+	}
+	addr.channel = "#" + channel
+
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		port := ircTLSPort
+		if !addr.tls {
+			port = ircPort
+		}
+		host = net.JoinHostPort(host, port)
+	}
+	addr.server = host
+
+	for _, option := range strings.Split(options, "&") {
+		key, value, _ := strings.Cut(option, "=")
+		switch key {
+		case "auth":
+			if value != "sasl" && value != "nickserv" && value != "pass" {
+				return ircAddress{}, fmt.Errorf("unknown irc auth method %q", value)
+			}
+			addr.auth = value
+		case "tls":
+			addr.tls = value != "0" && value != "false"
+		}
+	}
+
+	return addr, nil
+}
+
+func guestNick() string {
+	return fmt.Sprintf("combchats%04d", rand.Intn(10000))
+}
+
+type IRCChat struct {
+	health
+	address ircAddress
+	client  *irc.Client
+	stop    *stopSignal
+}
+
+func NewIRCChat(channelName string) (*IRCChat, error) {
+	address, err := parseIRCAddress(channelName)
+	if err != nil {
+		return nil, err
+	}
+	return &IRCChat{
+		address: address,
+		client:  irc.NewClient(irc.Config{Server: address.server, TLS: address.tls, Nick: guestNick()}),
+		stop:    newStopSignal(),
+	}, nil
+}
+
+func (ic *IRCChat) Start(output chan<- Message) {
+	go func() {
+		ic.client.OnMessage(func(msg irc.Message) {
+			if !strings.EqualFold(msg.Channel, ic.address.channel) {
+				return
+			}
+			ic.stop.send(output, Message{Text: msg.Text, Author: msg.Nick, Time: msg.Time})
+		})
+
+		ic.client.OnConnect(ic.connected)
+		ic.client.OnDisconnect(ic.disconnected)
+		ic.client.Join(ic.address.channel)
+
+		err := ic.client.Connect()
+		ic.disconnected(err)
+		if err != nil {
+			fmt.Printf("error in irc connect: %v\n", err)
+		}
+	}()
+}
+
+func (ic *IRCChat) Stop() {
+	ic.stop.signal()
+	ic.client.Disconnect()
+}
+
+var ErrIRCLoginTimeout = errors.New("irc login timed out")
+
+type IRCSender struct {
+	address ircAddress
+	client  *irc.Client
+}
+
+// NewIRCSender connects to IRC server as userName and joins the channel. Password is used according to auth option
+// of the channel name. It returns error if authentication fails or the channel isn't joined in time.
+func NewIRCSender(channelName, userName, password string) (*IRCSender, error) {
+	address, err := parseIRCAddress(channelName)
+	if err != nil {
+		return nil, err
+	}
+
+	config := irc.Config{Server: address.server, TLS: address.tls, Nick: userName}
+	if password != "" {
+		switch address.auth {
+		case "sasl":
+			config.SASLLogin = userName
+			config.SASLPassword = password
+		case "nickserv":
+			config.NickServPassword = password
+		case "pass":
+			config.Password = password
+		}
+	}
+
+	client := irc.NewClient(config)
+	joined := make(chan struct{})
+	var joinOnce sync.Once
+	client.OnJoin(func(channel string) {
+		if strings.EqualFold(channel, address.channel) {
+			joinOnce.Do(func() {
+				close(joined)
+			})
+		}
+	})
+	// Server drops connection with wrong password, the client reconnects, so the last error is reported on timeout.
+	var mu sync.Mutex
+	var lastErr error
+	client.OnDisconnect(func(err error) {
+		mu.Lock()
+		lastErr = err
+		mu.Unlock()
+	})
+	client.Join(address.channel)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Connect()
+	}()
+
+	select {
+	case <-joined:
+		return &IRCSender{
+			address: address,
+			client:  client,
+		}, nil
+	case err := <-done:
+		return nil, fmt.Errorf("irc login as %s: %w", userName, err)
+	case <-time.After(ircLoginTimeout):
+		client.Disconnect()
+		mu.Lock()
+		defer mu.Unlock()
+		if lastErr != nil {
+			return nil, fmt.Errorf("%w: %v", ErrIRCLoginTimeout, lastErr)
+		}
+		return nil, ErrIRCLoginTimeout
+	}
+}
+
+func (is *IRCSender) Send(msg string) error {
+	return is.client.Say(is.address.channel, msg)
+}
+
+// Delete isn't supported: IRC messages have no ids and can't be deleted.
+func (is *IRCSender) Delete(id string) error {
+	return ErrModerationNotSupported
+}
+
+// Ban bans and kicks nick, sender account must be an operator of the channel.
+// IRC has no timeouts, so temporary ban is removed after duration.
+func (is *IRCSender) Ban(user string, duration time.Duration) error {
+	if err := is.client.Ban(is.address.channel, user); err != nil {
+		return err
+	}
+	if duration > 0 {
+		time.AfterFunc(duration, func() {
+			if err := is.client.Unban(is.address.channel, user); err != nil {
+				fmt.Printf("error in irc unban: %v\n", err)
+			}
+		})
+	}
+	return nil
+}
+
+// Sanitize removes control characters, which start CTCP requests and formatting, and invisible characters.
+func (is *IRCSender) Sanitize(msg string) string {
+	return stripInvisible(msg)
+}
+
+// Limits returns length of text which fits into 512 bytes IRC line together with command, channel and prefix
+// added by server when message is relayed.
+func (is *IRCSender) Limits() Limits {
+	return Limits{MaxBytes: 400 - len(is.address.channel)}
+}
+
+func (is *IRCSender) Stop() {
+	is.client.Disconnect()
+}
@@ -0,0 +1,95 @@
+package chat
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/MrMamka/combchats/pkg/irc"
+)
+
+// serveIRC accepts connections and answers every line from client with lines returned by reply.
+func serveIRC(t *testing.T, reply func(line string) []string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					for _, answer := range reply(strings.TrimRight(line, "\r\n")) {
+						fmt.Fprintf(conn, "%s\r\n", answer)
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestNewIRCSender(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		reply    func(line string) []string
+		wantErr  error
+	}{
+		{
+			name: "joined",
+			reply: func(line string) []string {
+				switch {
+				case strings.HasPrefix(line, "USER "):
+					return []string{":server 001 sender :Welcome"}
+				case line == "JOIN #chan":
+					return []string{":sender!sender@host JOIN #chan"}
+				}
+				return nil
+			},
+		},
+		{
+			name:     "sasl rejected",
+			password: "secret",
+			reply: func(line string) []string {
+				if line == "CAP REQ :sasl" {
+					return []string{":server CAP * NAK :sasl"}
+				}
+				return nil
+			},
+			wantErr: irc.ErrSASLFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := serveIRC(t, tt.reply)
+
+			sender, err := NewIRCSender("irc://"+server+"/chan", "sender", tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewIRCSender() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				defer sender.Stop()
+				if err := sender.Send("hi"); err != nil {
+					t.Errorf("Send() error = %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MrMamka/combchats/pkg/matrix"
+)
+
+var ErrMatrixNotConfigured = errors.New("matrix account for reading rooms is not configured")
+
+type MatrixConfig struct {
+	// Homeserver used when channel name doesn't contain one.
+	Homeserver string
+	// AccessToken of the account which reads matrix rooms.
+	AccessToken string
+}
+
+// Matrix is the account used by MatrixChat. It is filled by the bot from environment.
+var Matrix MatrixConfig
+
+// parseMatrixAddress splits channel name in format [https://homeserver/]room,
+// where room is room id (!id:server) or alias (#alias:server).
+func parseMatrixAddress(name string) (homeserver, room string, err error) {
+	room = name
+	if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+		idx := strings.LastIndex(name, "/!")
+		if aliasIdx := strings.LastIndex(name, "/#"); aliasIdx > idx {
+			idx = aliasIdx
+		}
+		if idx < 0 {
+			return "", "", fmt.Errorf("matrix channel %q has no room", name)
+		}
+		homeserver, room = name[:idx], name[idx+1:]
+	}
+
+	if !strings.HasPrefix(room, "!") && !strings.HasPrefix(room, "#") {
+		room = "#" + room
+	}
+	_, server, ok := strings.Cut(room, ":")
+	if !ok || server == "" {
+		return "", "", fmt.Errorf("matrix room %q must be in format #alias:server or !id:server", room)
+	}
+
+	switch {
+	case homeserver != "":
+	case Matrix.Homeserver != "":
+		homeserver = Matrix.Homeserver
+	default:
+		homeserver = "https://" + server
+	}
+	return homeserver, room, nil
+}
+
+// matrixDisplayName returns localpart of matrix user id.
+func matrixDisplayName(userID string) string {
+	name, _, _ := strings.Cut(strings.TrimPrefix(userID, "@"), ":")
+	return name
+}
+
+type MatrixChat struct {
+	health
+	room   string
+	client *matrix.Client
+	stop   *stopSignal
+}
+
+func NewMatrixChat(channelName string) (*MatrixChat, error) {
+	if Matrix.AccessToken == "" {
+		return nil, ErrMatrixNotConfigured
+	}
+	homeserver, room, err := parseMatrixAddress(channelName)
+	if err != nil {
+		return nil, err
+	}
+	return &MatrixChat{
+		room:   room,
+		client: matrix.NewClient(homeserver, Matrix.AccessToken),
+		stop:   newStopSignal(),
+	}, nil
+}
+
+func (mc *MatrixChat) Start(output chan<- Message) {
+	go func() {
+		mc.client.OnMessage(func(msg matrix.Message) {
+			mc.stop.send(output, Message{
+				ID:        msg.ID,
+				Text:      msg.Body,
+				Author:    matrixDisplayName(msg.Sender),
+				AuthorURL: "https://matrix.to/#/" + msg.Sender,
+				Time:      msg.Time,
+				ReplyTo:   msg.ReplyTo,
+			})
+		})
+
+		mc.client.OnSync(func(err error) {
+			if err != nil {
+				mc.disconnected(err)
+			} else {
+				mc.connected()
+			}
+		})
+
+		if err := mc.client.Join(mc.room); err != nil {
+			mc.disconnected(err)
+			fmt.Printf("error in matrix join: %v\n", err)
+			return
+		}
+
+		err := mc.client.Connect()
+		mc.disconnected(err)
+		if err != nil {
+			fmt.Printf("error in matrix connect: %v\n", err)
+		}
+	}()
+}
+
+func (mc *MatrixChat) Stop() {
+	mc.stop.signal()
+	mc.client.Disconnect()
+}
+
+type MatrixSender struct {
+	client *matrix.Client
+}
+
+// NewMatrixSender joins the room with the account of access token.
+func NewMatrixSender(channelName, accessToken string) (*MatrixSender, error) {
+	homeserver, room, err := parseMatrixAddress(channelName)
+	if err != nil {
+		return nil, err
+	}
+
+	client := matrix.NewClient(homeserver, accessToken)
+	if err := client.Join(room); err != nil {
+		return nil, err
+	}
+
+	return &MatrixSender{
+		client: client,
+	}, nil
+}
+
+func (ms *MatrixSender) Send(msg string) error {
+	_, err := ms.client.SendMessage(msg)
+	return err
+}
+
+func (ms *MatrixSender) SendReply(msg, replyTo string) (string, error) {
+	return ms.client.SendReply(msg, replyTo)
+}
+
+func (ms *MatrixSender) Delete(id string) error {
+	return ms.client.Redact(id, "")
+}
+
+// Ban isn't supported: matrix bans by user id, which is unknown for users of other platforms.
+func (ms *MatrixSender) Ban(user string, duration time.Duration) error {
+	return ErrModerationNotSupported
+}
+
+// Sanitize neutralizes @room mention notifying everyone in the room and removes invisible characters.
+func (ms *MatrixSender) Sanitize(msg string) string {
+	return strings.ReplaceAll(stripInvisible(msg), "@room", "\uff20room")
+}
+
+// Limits returns length of text which fits into 65536 bytes matrix event together with its other fields.
+func (ms *MatrixSender) Limits() Limits {
+	return Limits{MaxBytes: 60000}
+}
+
+func (ms *MatrixSender) Stop() {
+	ms.client.Disconnect()
+}
@@ -0,0 +1,126 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrModerationNotSupported = errors.New("moderation action is not supported for this channel type")
+
+// Moderator is implemented by senders which can moderate the destination with the sender account.
+type Moderator interface {
+	// Delete deletes message with the ID in destination.
+	Delete(id string) error
+	// Ban bans user with the name in destination. Zero duration means permanent ban.
+	Ban(user string, duration time.Duration) error
+}
+
+// ModerationPolicy tells which moderation actions in the source of route are repeated in the destination.
+type ModerationPolicy int
+
+const (
+	// NoModeration doesn't repeat anything, it is the default.
+	NoModeration ModerationPolicy = iota
+	// DeleteCopies deletes copies of messages deleted in the source and of messages of users banned there.
+	DeleteCopies
+	// MirrorBans deletes copies and bans the user with the same name in the destination.
+	MirrorBans
+)
+
+// moderation is an action of the source moderators repeated in destination.
+type moderation struct {
+	// deleted are original messages which copies are deleted.
+	deleted []messageKey
+	// ban is the name of user banned for duration.
+	ban      string
+	duration time.Duration
+}
+
+// SetRouteModeration sets which moderation actions are repeated on the route. It can be called while router works.
+func (r *Router) SetRouteModeration(route Route, policy ModerationPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.moderations[route] = policy
+}
+
+// Moderation returns which moderation actions are repeated on the route.
+func (r *Router) Moderation(route Route) ModerationPolicy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.moderations[route]
+}
+
+// mirrorModeration queues moderation action of the source to destinations of routes which opted in.
+func (r *Router) mirrorModeration(from Channel, msg Message, queues map[Channel]chan outgoing) {
+	var action moderation
+	switch msg.Event.Type {
+	case DeleteMessageEvent:
+		t, ok := r.threads.Lookup(messageKey{channel: from, id: msg.Event.TargetID})
+		// Deletion of our copy is not repeated for the original and other copies.
+		if !ok || t.original.channel != from || t.original.id != msg.Event.TargetID {
+			return
+		}
+		action.deleted = []messageKey{t.original}
+	case BanEvent:
+		if r.isOwnName(from, msg.Event.TargetUser) {
+			return
+		}
+		for _, t := range r.threads.ByAuthor(from, msg.Event.TargetUser) {
+			action.deleted = append(action.deleted, t.original)
+		}
+		action.ban = msg.Event.TargetUser
+		action.duration = msg.Event.Duration
+	default:
+		return
+	}
+
+	for _, to := range r.routes[from] {
+		policy := r.Moderation(Route{From: from, To: to})
+		if policy == NoModeration {
+			continue
+		}
+
+		routeAction := action
+		if policy != MirrorBans {
+			routeAction.ban = ""
+		}
+		if len(routeAction.deleted) == 0 && routeAction.ban == "" {
+			continue
+		}
+
+		select {
+		case queues[to] <- outgoing{moderation: &routeAction}:
+		default:
+			fmt.Printf("queue of %v is full, moderation action dropped\n", to)
+		}
+	}
+}
+
+// moderate repeats action in destination. Copies are looked up when action is executed,
+// so copies sent right before it are deleted too.
+func (r *Router) moderate(to Channel, sender Sender, action moderation) {
+	moderator, ok := sender.(Moderator)
+	if !ok {
+		fmt.Printf("error in moderation of %v: %v\n", to, ErrModerationNotSupported)
+		return
+	}
+
+	for _, original := range action.deleted {
+		t, ok := r.threads.Lookup(original)
+		if !ok {
+			continue
+		}
+		for _, id := range t.copies[to] {
+			if err := moderator.Delete(id); err != nil {
+				fmt.Printf("error in deleting message in %v: %v\n", to, err)
+			}
+		}
+	}
+
+	if action.ban != "" {
+		if err := moderator.Ban(action.ban, action.duration); err != nil {
+			fmt.Printf("error in banning %s in %v: %v\n", action.ban, to, err)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package chat
+
+import (
+	"strings"
+	"unicode"
+)
+
+// invisibleRunes are characters which are not shown, but can hide commands from filters
+// or change the order of text.
+var invisibleRunes = map[rune]bool{
+	'\u00ad': true,                                                                 // soft hyphen
+	'\u200b': true,                                                                 // zero width space
+	'\u200c': true,                                                                 // zero width non-joiner
+	'\u200d': true,                                                                 // zero width joiner, kept inside emoji sequences
+	'\u200e': true,                                                                 // left-to-right mark
+	'\u200f': true,                                                                 // right-to-left mark
+	'\u202a': true, '\u202b': true, '\u202c': true, '\u202d': true, '\u202e': true, // bidi embeddings and overrides
+	'\u2060': true,                                                 // word joiner
+	'\u2066': true, '\u2067': true, '\u2068': true, '\u2069': true, // bidi isolates
+	'\ufeff': true, // zero width no-break space
+}
+
+// stripInvisible removes invisible and control characters except line breaks and tabs.
+func stripInvisible(text string) string {
+	runes := []rune(text)
+	var builder strings.Builder
+	for i, r := range runes {
+		if r == '\u200d' && i > 0 && i+1 < len(runes) && isEmoji(runes[i-1]) && isEmoji(runes[i+1]) {
+			builder.WriteRune(r)
+			continue
+		}
+		if invisibleRunes[r] || (unicode.IsControl(r) && r != '\n' && r != '\t') {
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+func isEmoji(r rune) bool {
+	return unicode.Is(unicode.So, r) || unicode.Is(unicode.Sk, r) || (r >= '\ufe00' && r <= '\ufe0f')
+}
+
+// commandLookalikes replace the first character of text which platforms treat as command prefix.
+var commandLookalikes = map[rune]string{
+	'/': "\u2215", // division slash
+	'.': "\u2024", // one dot leader
+}
+
+// neutralizeCommand replaces command prefix at the beginning of text with a similar looking character.
+// Platforms trim leading spaces, so they are removed too.
+func neutralizeCommand(text string, prefixes string) string {
+	text = strings.TrimLeftFunc(text, unicode.IsSpace)
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(text, string(prefix)) {
+			return commandLookalikes[prefix] + strings.TrimPrefix(text, string(prefix))
+		}
+	}
+	return text
+}
+
+// SanitizeMessage cleans fields of the message written by users before it is formatted, so they can't impersonate
+// other authors or messages of the bot: author name loses line breaks, brackets and colons used by templates,
+// lines of text can't start with a platform label.
+func SanitizeMessage(msg Message) Message {
+	author := strings.Join(strings.Fields(stripInvisible(msg.Author)), " ")
+	msg.Author = strings.NewReplacer("[", "(", "]", ")", ":", "\ua789").Replace(author)
+
+	lines := strings.Split(stripInvisible(msg.Text), "\n")
+	for i := 1; i < len(lines); i++ {
+		if strings.HasPrefix(strings.TrimLeftFunc(lines[i], unicode.IsSpace), "[") {
+			lines[i] = "› " + lines[i]
+		}
+	}
+	msg.Text = strings.Join(lines, "\n")
+
+	msg.ReplyAuthor = strings.Join(strings.Fields(stripInvisible(msg.ReplyAuthor)), " ")
+	// Offsets of emotes don't match the cleaned text, emotes must be translated before.
+	msg.Emotes = nil
+	return msg
+}
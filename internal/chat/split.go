@@ -0,0 +1,99 @@
+package chat
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const ellipsis = "…"
+
+// Limits are restrictions of the platform on length of one message. Zero means no restriction.
+type Limits struct {
+	MaxRunes int
+	MaxBytes int
+}
+
+func (l Limits) fits(text string) bool {
+	return (l.MaxRunes == 0 || utf8.RuneCountInString(text) <= l.MaxRunes) &&
+		(l.MaxBytes == 0 || len(text) <= l.MaxBytes)
+}
+
+// reserve returns limits for text which will be followed by suffix.
+func (l Limits) reserve(suffix string) Limits {
+	if l.MaxRunes != 0 {
+		l.MaxRunes -= utf8.RuneCountInString(suffix)
+	}
+	if l.MaxBytes != 0 {
+		l.MaxBytes -= len(suffix)
+	}
+	return l
+}
+
+// LengthPolicy tells what to do with messages longer than the limit of destination.
+type LengthPolicy int
+
+const (
+	// TruncateLongMessages cuts message and adds ellipsis.
+	TruncateLongMessages LengthPolicy = iota
+	// SplitLongMessages sends message in several parts split on word boundaries.
+	SplitLongMessages
+)
+
+// maxSplitParts limits number of parts of one message, the last part is truncated.
+const maxSplitParts = 5
+
+// FitMessage makes parts of text which fit into limits according to policy.
+func FitMessage(text string, limits Limits, policy LengthPolicy) []string {
+	if limits.fits(text) {
+		return []string{text}
+	}
+
+	if policy == TruncateLongMessages {
+		head, _ := cutWords(text, limits.reserve(ellipsis))
+		return []string{head + ellipsis}
+	}
+
+	var parts []string
+	rest := text
+	for rest != "" {
+		if len(parts) == maxSplitParts-1 && !limits.fits(rest) {
+			head, _ := cutWords(rest, limits.reserve(ellipsis))
+			return append(parts, head+ellipsis)
+		}
+		head, tail := cutWords(rest, limits)
+		parts = append(parts, head)
+		rest = tail
+	}
+	return parts
+}
+
+// cutWords returns the longest prefix of text fitting into limits, cut on word boundary when possible,
+// and the rest of text.
+func cutWords(text string, limits Limits) (string, string) {
+	if limits.fits(text) {
+		return text, ""
+	}
+
+	end, runes := 0, 0
+	for i, r := range text {
+		size := utf8.RuneLen(r)
+		if (limits.MaxRunes != 0 && runes+1 > limits.MaxRunes) || (limits.MaxBytes != 0 && i+size > limits.MaxBytes) {
+			break
+		}
+		end = i + size
+		runes++
+	}
+	if end == 0 {
+		// Limit is smaller than one character, nothing reasonable can be done.
+		_, size := utf8.DecodeRuneInString(text)
+		end = size
+	}
+
+	// Cut on the last space if it doesn't make the part too short.
+	if space := strings.LastIndexFunc(text[:end], unicode.IsSpace); space > end/2 {
+		end = space
+	}
+
+	return strings.TrimRightFunc(text[:end], unicode.IsSpace), strings.TrimLeftFunc(text[end:], unicode.IsSpace)
+}
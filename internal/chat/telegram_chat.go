@@ -0,0 +1,253 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const telegramQueueSize = 100
+
+// TelegramMessageLimit is the maximum length of telegram message text, markup is not counted.
+const TelegramMessageLimit = 4096
+
+var ErrTelegramNotReady = errors.New("telegram bot is not started")
+
+// TelegramHub connects telegram groups to the update stream of the bot.
+//
+// Bot passes every incoming message to Dispatch, and hub delivers it to the chats listening that group.
+type TelegramHub struct {
+	mu    sync.RWMutex
+	api   *tgbotapi.BotAPI
+	chats map[*TelegramChat]struct{}
+}
+
+// Telegram is the hub used by TelegramChat and TelegramSender. It must be bound to the bot by SetAPI.
+var Telegram = &TelegramHub{chats: make(map[*TelegramChat]struct{})}
+
+func (th *TelegramHub) SetAPI(api *tgbotapi.BotAPI) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	th.api = api
+}
+
+func (th *TelegramHub) API() *tgbotapi.BotAPI {
+	th.mu.RLock()
+	defer th.mu.RUnlock()
+	return th.api
+}
+
+// Dispatch delivers message to all chats listening its group. Returns true if at least one chat accepted it.
+func (th *TelegramHub) Dispatch(msg *tgbotapi.Message) bool {
+	if msg == nil || msg.Chat == nil {
+		return false
+	}
+
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+
+	th.mu.RLock()
+	defer th.mu.RUnlock()
+
+	accepted := false
+	for tc := range th.chats {
+		if !tc.matches(msg.Chat) {
+			continue
+		}
+		accepted = true
+		if text == "" {
+			continue
+		}
+
+		result := Message{
+			ID:        strconv.Itoa(msg.MessageID),
+			Text:      text,
+			Author:    telegramAuthor(msg.From),
+			AuthorURL: telegramAuthorURL(msg.From),
+			Time:      time.Unix(int64(msg.Date), 0),
+		}
+		if parent := msg.ReplyToMessage; parent != nil {
+			result.ReplyTo = strconv.Itoa(parent.MessageID)
+			result.ReplyAuthor = telegramAuthor(parent.From)
+		}
+
+		select {
+		case tc.queue <- result:
+		default:
+			fmt.Printf("telegram chat %s queue is full, message dropped\n", tc.channelName)
+		}
+	}
+	return accepted
+}
+
+func (th *TelegramHub) register(tc *TelegramChat) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	th.chats[tc] = struct{}{}
+}
+
+func (th *TelegramHub) unregister(tc *TelegramChat) {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	delete(th.chats, tc)
+}
+
+func telegramAuthor(user *tgbotapi.User) string {
+	if user == nil {
+		return ""
+	}
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		return user.UserName
+	}
+	return name
+}
+
+// telegramAuthorURL returns link to the user, which exists only for users with public @username.
+func telegramAuthorURL(user *tgbotapi.User) string {
+	if user == nil || user.UserName == "" {
+		return ""
+	}
+	return "https://t.me/" + user.UserName
+}
+
+// telegramChatID parses channel name, which is either numeric chat id or public @username.
+func telegramChatID(channelName string) (int64, bool) {
+	id, err := strconv.ParseInt(channelName, 10, 64)
+	return id, err == nil
+}
+
+type TelegramChat struct {
+	health
+	channelName string
+	hub         *TelegramHub
+	queue       chan Message
+	stop        chan struct{}
+}
+
+func NewTelegramChat(channelName string) *TelegramChat {
+	return &TelegramChat{
+		channelName: channelName,
+		hub:         Telegram,
+		queue:       make(chan Message, telegramQueueSize),
+		stop:        make(chan struct{}),
+	}
+}
+
+func (tc *TelegramChat) matches(c *tgbotapi.Chat) bool {
+	if id, ok := telegramChatID(tc.channelName); ok {
+		return c.ID == id
+	}
+	return strings.EqualFold(strings.TrimPrefix(tc.channelName, "@"), c.UserName)
+}
+
+func (tc *TelegramChat) Start(output chan<- Message) {
+	tc.hub.register(tc)
+	tc.connected()
+
+	go func() {
+		for {
+			select {
+			case msg := <-tc.queue:
+				select {
+				case output <- msg:
+				case <-tc.stop:
+					return
+				}
+			case <-tc.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (tc *TelegramChat) Stop() {
+	tc.hub.unregister(tc)
+	tc.disconnected(nil)
+	close(tc.stop)
+}
+
+type TelegramSender struct {
+	channelName string
+	hub         *TelegramHub
+}
+
+func NewTelegramSender(channelName string) *TelegramSender {
+	return &TelegramSender{
+		channelName: channelName,
+		hub:         Telegram,
+	}
+}
+
+func (ts *TelegramSender) Send(msg string) error {
+	_, err := ts.SendReply(msg, "")
+	return err
+}
+
+func (ts *TelegramSender) SendReply(msg, replyTo string) (string, error) {
+	api := ts.hub.API()
+	if api == nil {
+		return "", ErrTelegramNotReady
+	}
+
+	var msgResp tgbotapi.MessageConfig
+	if id, ok := telegramChatID(ts.channelName); ok {
+		msgResp = tgbotapi.NewMessage(id, msg)
+	} else {
+		msgResp = tgbotapi.NewMessageToChannel("@"+strings.TrimPrefix(ts.channelName, "@"), msg)
+	}
+	if replyTo != "" {
+		msgResp.ReplyToMessageID, _ = strconv.Atoi(replyTo)
+		msgResp.AllowSendingWithoutReply = true
+	}
+
+	sent, err := api.Send(msgResp)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(sent.MessageID), nil
+}
+
+func (ts *TelegramSender) Delete(id string) error {
+	api := ts.hub.API()
+	if api == nil {
+		return ErrTelegramNotReady
+	}
+
+	messageID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid telegram message id %q", id)
+	}
+	config := tgbotapi.DeleteMessageConfig{MessageID: messageID}
+	if chatID, ok := telegramChatID(ts.channelName); ok {
+		config.ChatID = chatID
+	} else {
+		config.ChannelUsername = "@" + strings.TrimPrefix(ts.channelName, "@")
+	}
+	_, err = api.Request(config)
+	return err
+}
+
+// Ban isn't supported: telegram bans by user id, which is unknown for users of other platforms.
+func (ts *TelegramSender) Ban(user string, duration time.Duration) error {
+	return ErrModerationNotSupported
+}
+
+// Sanitize removes invisible characters. Messages are sent without markup, so other syntax is harmless.
+func (ts *TelegramSender) Sanitize(msg string) string {
+	return stripInvisible(msg)
+}
+
+// Limits returns maximum length of telegram message.
+func (ts *TelegramSender) Limits() Limits {
+	return Limits{MaxRunes: TelegramMessageLimit}
+}
+
+func (ts *TelegramSender) Stop() {}
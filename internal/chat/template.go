@@ -0,0 +1,235 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/MrMamka/combchats/internal/i18n"
+)
+
+const (
+	PlatformField = "platform"
+	ChannelField  = "channel"
+	AuthorField   = "author"
+	TextField     = "text"
+	TimeField     = "time"
+	BadgesField   = "badges"
+)
+
+var (
+	ErrTemplateWithoutText = errors.New("template must contain {text}")
+	ErrUnclosedField       = errors.New("template has unclosed {")
+)
+
+var roleBadges = map[Role]string{
+	BroadcasterRole: "🎥",
+	ModeratorRole:   "🗡",
+	VIPRole:         "💎",
+	SubscriberRole:  "⭐",
+}
+
+// platformIcons start messages formatted as HTML, so the source is seen at a glance.
+var platformIcons = map[ChannelType]string{
+	TwitchChannelType:   "🟣",
+	VkChannelType:       "🔵",
+	TelegramChannelType: "✈️",
+	IRCChannelType:      "💬",
+	MatrixChannelType:   "🟢",
+	WebhookChannelType:  "🔗",
+}
+
+// DefaultTemplate is the format of messages used when nothing else is configured.
+var DefaultTemplate = MustParseTemplate("{author}: {text}")
+
+// DefaultCombinedTemplate is the format of messages in combined chat, where time and roles of authors are shown.
+var DefaultCombinedTemplate = MustParseTemplate("{time} {badges}{author}: {text}")
+
+type templatePart struct {
+	literal string
+	field   string
+}
+
+// Template formats message to text. Fields in braces are replaced by values of the message:
+//
+//	{platform} - platform of the source (or label of the message, if it has one)
+//	{channel}  - name of the source channel
+//	{author}   - author of the message
+//	{text}     - text of the message
+//	{time}     - time of the message in HH:MM:SS
+//	{badges}   - badges of author roles followed by space, or nothing if author has no roles
+//
+// Notifications and moderation events are formatted as the part of template before {author} or {text}
+// followed by the event description.
+type Template struct {
+	raw   string
+	parts []templatePart
+	// lang is the language of event descriptions.
+	lang i18n.Language
+}
+
+func ParseTemplate(raw string) (Template, error) {
+	t := Template{raw: raw}
+	hasText := false
+
+	rest := raw
+	for rest != "" {
+		open := strings.Index(rest, "{")
+		if open < 0 {
+			t.parts = append(t.parts, templatePart{literal: rest})
+			break
+		}
+		if open > 0 {
+			t.parts = append(t.parts, templatePart{literal: rest[:open]})
+		}
+
+		end := strings.Index(rest[open:], "}")
+		if end < 0 {
+			return Template{}, ErrUnclosedField
+		}
+		field := rest[open+1 : open+end]
+		switch field {
+		case PlatformField, ChannelField, AuthorField, TimeField, BadgesField:
+		case TextField:
+			hasText = true
+		default:
+			return Template{}, fmt.Errorf("unknown template field {%s}", field)
+		}
+		t.parts = append(t.parts, templatePart{field: field})
+		rest = rest[open+end+1:]
+	}
+
+	if !hasText {
+		return Template{}, ErrTemplateWithoutText
+	}
+	return t, nil
+}
+
+func MustParseTemplate(raw string) Template {
+	t, err := ParseTemplate(raw)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func (t Template) String() string {
+	return t.raw
+}
+
+// In returns the template which describes events in lang.
+func (t Template) In(lang i18n.Language) Template {
+	t.lang = lang
+	return t
+}
+
+// Format formats message as plain text.
+func (t Template) Format(msg Message) string {
+	return t.format(msg, false, false)
+}
+
+// FormatHTML formats message in telegram HTML. Message starts with icon of its platform, author is bold
+// and links to AuthorURL if links is true, time is monospace and notifications are italic. Values are escaped.
+func (t Template) FormatHTML(msg Message, links bool) string {
+	return t.format(msg, true, links)
+}
+
+func (t Template) format(msg Message, rich, links bool) string {
+	escape := func(text string) string {
+		if rich {
+			return html.EscapeString(text)
+		}
+		return text
+	}
+
+	var builder strings.Builder
+	if icon, ok := platformIcons[msg.Source.Type]; ok && rich {
+		builder.WriteString(icon + " ")
+	}
+	// Label of the message is always shown, even if template has no {platform}.
+	if msg.Platform != "" && !t.hasField(PlatformField) {
+		fmt.Fprintf(&builder, "[%s] ", escape(msg.Platform))
+	}
+
+	for _, part := range t.parts {
+		if part.field == "" {
+			builder.WriteString(escape(part.literal))
+			continue
+		}
+
+		if msg.Event.Type != MessageEvent && (part.field == AuthorField || part.field == TextField) {
+			text := escape(eventToText(msg, t.lang))
+			if rich {
+				text = "<i>" + text + "</i>"
+			}
+			builder.WriteString(text)
+			break
+		}
+
+		value := escape(fieldValue(part.field, msg))
+		if rich {
+			value = richFieldValue(part.field, value, msg, links)
+		}
+		builder.WriteString(value)
+	}
+
+	return builder.String()
+}
+
+// richFieldValue adds HTML markup to escaped value of the field.
+func richFieldValue(field, value string, msg Message, links bool) string {
+	switch field {
+	case AuthorField:
+		value = "<b>" + value + "</b>"
+		if links && msg.AuthorURL != "" {
+			value = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(msg.AuthorURL), value)
+		}
+	case TimeField:
+		value = "<code>" + value + "</code>"
+	}
+	return value
+}
+
+func (t Template) hasField(field string) bool {
+	for _, part := range t.parts {
+		if part.field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldValue(field string, msg Message) string {
+	switch field {
+	case PlatformField:
+		if msg.Platform != "" {
+			return msg.Platform
+		}
+		return msg.Source.Type.String()
+	case ChannelField:
+		return msg.Source.Name
+	case AuthorField:
+		return msg.Author
+	case TextField:
+		return msg.Text
+	case TimeField:
+		msgTime := msg.Time
+		if msgTime.IsZero() {
+			msgTime = time.Now()
+		}
+		return msgTime.Local().Format("15:04:05")
+	case BadgesField:
+		var badges strings.Builder
+		for _, role := range msg.Roles {
+			badges.WriteString(roleBadges[role])
+		}
+		if badges.Len() == 0 {
+			return ""
+		}
+		return badges.String() + " "
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,104 @@
+package chat
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		raw     string
+		wantErr bool
+		// errIs is the sentinel error, if the error has one.
+		errIs error
+	}{
+		{raw: "{author}: {text}"},
+		{raw: "[{platform}/{channel}] {time} {badges}{author}: {text}"},
+		{raw: "{text}"},
+		{raw: "{author}: no text", wantErr: true, errIs: ErrTemplateWithoutText},
+		{raw: "", wantErr: true, errIs: ErrTemplateWithoutText},
+		{raw: "{text} {author", wantErr: true, errIs: ErrUnclosedField},
+		{raw: "{author: {text}", wantErr: true},
+		{raw: "{text} {unknown}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseTemplate(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTemplate(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if tt.errIs != nil && !errors.Is(err, tt.errIs) {
+				t.Fatalf("ParseTemplate(%q) error = %v, want %v", tt.raw, err, tt.errIs)
+			}
+			if err == nil && got.String() != tt.raw {
+				t.Errorf("ParseTemplate(%q).String() = %q", tt.raw, got.String())
+			}
+		})
+	}
+}
+
+func TestTemplateFormat(t *testing.T) {
+	msg := Message{
+		Text:   "hello",
+		Author: "viewer",
+		Time:   time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local),
+		Roles:  []Role{ModeratorRole, SubscriberRole},
+		Source: Channel{Type: TwitchChannelType, Name: "streamer"},
+	}
+
+	tests := []struct {
+		raw  string
+		msg  Message
+		want string
+	}{
+		{raw: "{author}: {text}", msg: msg, want: "viewer: hello"},
+		{raw: "[{platform}/{channel}] {text}", msg: msg, want: "[Twitch/streamer] hello"},
+		{raw: "{time} {badges}{author}: {text}", msg: msg, want: "15:04:05 🗡⭐ viewer: hello"},
+		{raw: "{badges}{author}: {text}", msg: Message{Author: "a", Text: "b"}, want: "a: b"},
+		{raw: "{author}: {text}", msg: Message{Author: "a", Text: "b", Platform: "label"}, want: "[label] a: b"},
+		{raw: "{platform}: {text}", msg: Message{Text: "b", Platform: "label"}, want: "label: b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if got := MustParseTemplate(tt.raw).Format(tt.msg); got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateFormatHTML(t *testing.T) {
+	msg := Message{
+		Text:      "<b>not bold</b> & co",
+		Author:    "<script>",
+		AuthorURL: `https://example.com/?a="1"`,
+		Time:      time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local),
+		Source:    Channel{Type: TwitchChannelType, Name: "streamer"},
+	}
+	template := MustParseTemplate("{time} <{author}> {text}")
+
+	tests := []struct {
+		name  string
+		links bool
+		want  string
+	}{
+		{
+			name: "without links",
+			want: "🟣 <code>15:04:05</code> &lt;<b>&lt;script&gt;</b>&gt; &lt;b&gt;not bold&lt;/b&gt; &amp; co",
+		},
+		{
+			name:  "with links",
+			links: true,
+			want: "🟣 <code>15:04:05</code> &lt;<a href=\"https://example.com/?a=&#34;1&#34;\"><b>&lt;script&gt;</b></a>&gt; " +
+				"&lt;b&gt;not bold&lt;/b&gt; &amp; co",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := template.FormatHTML(msg, tt.links); got != tt.want {
+				t.Errorf("FormatHTML() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package chat
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	threadCacheCapacity = 1000
+	threadCacheTTL      = time.Hour
+)
+
+// TrackedSender is implemented by senders which report ID of sent message.
+type TrackedSender interface {
+	SendTracked(text string) (string, error)
+}
+
+// ReplySender is implemented by senders which report ID of sent message and can send native replies.
+type ReplySender interface {
+	// SendReply sends text as reply to message replyTo of the destination, or as usual message if replyTo is empty.
+	SendReply(text, replyTo string) (string, error)
+}
+
+type messageKey struct {
+	channel Channel
+	id      string
+}
+
+// thread is a forwarded message together with IDs of its copies in destinations.
+type thread struct {
+	original messageKey
+	author   string
+	// copies holds IDs of all parts of the message sent to each destination.
+	copies  map[Channel][]string
+	expires time.Time
+}
+
+// idIn returns ID of the message or the first part of its copy in channel.
+func (t thread) idIn(channel Channel) (string, bool) {
+	if t.original.channel == channel {
+		return t.original.id, true
+	}
+	ids := t.copies[channel]
+	if len(ids) == 0 {
+		return "", false
+	}
+	return ids[0], true
+}
+
+// threadCache maps IDs of forwarded messages and their copies to each other. Threads expire after ttl,
+// and the oldest threads are evicted when capacity is reached. It is safe for concurrent use.
+type threadCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	// entries holds elements of order for IDs of originals and copies.
+	entries map[messageKey]*list.Element
+	// order holds threads from the oldest to the newest.
+	order *list.List
+}
+
+func newThreadCache(capacity int, ttl time.Duration) *threadCache {
+	return &threadCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[messageKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Add records message which is forwarded. Messages without ID are not recorded.
+func (tc *threadCache) Add(original messageKey, author string) {
+	if original.id == "" {
+		return
+	}
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	now := time.Now()
+	tc.evictExpired(now)
+
+	if _, ok := tc.entries[original]; ok {
+		return
+	}
+	tc.entries[original] = tc.order.PushBack(&thread{
+		original: original,
+		author:   author,
+		copies:   make(map[Channel][]string),
+		expires:  now.Add(tc.ttl),
+	})
+
+	for tc.order.Len() > tc.capacity {
+		tc.remove(tc.order.Front())
+	}
+}
+
+// AddCopy records ID of the copy of original message sent to channel.
+func (tc *threadCache) AddCopy(original messageKey, channel Channel, id string) {
+	if original.id == "" || id == "" {
+		return
+	}
+
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	elem, ok := tc.entries[original]
+	if !ok {
+		return
+	}
+	key := messageKey{channel: channel, id: id}
+	if _, ok := tc.entries[key]; ok {
+		// The copy is already known, it is reported both by sender and by echo.
+		return
+	}
+	t := elem.Value.(*thread)
+	t.copies[channel] = append(t.copies[channel], id)
+	tc.entries[key] = elem
+}
+
+// Lookup returns thread of the message which is an original or a copy.
+func (tc *threadCache) Lookup(key messageKey) (thread, bool) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	tc.evictExpired(time.Now())
+
+	elem, ok := tc.entries[key]
+	if !ok {
+		return thread{}, false
+	}
+	return elem.Value.(*thread).clone(), true
+}
+
+// ByAuthor returns recent threads of messages written by author in channel.
+func (tc *threadCache) ByAuthor(channel Channel, author string) []thread {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	tc.evictExpired(time.Now())
+
+	var threads []thread
+	for elem := tc.order.Front(); elem != nil; elem = elem.Next() {
+		t := elem.Value.(*thread)
+		if t.original.channel == channel && strings.EqualFold(t.author, author) {
+			threads = append(threads, t.clone())
+		}
+	}
+	return threads
+}
+
+func (t *thread) clone() thread {
+	result := *t
+	result.copies = make(map[Channel][]string, len(t.copies))
+	for channel, ids := range t.copies {
+		result.copies[channel] = append([]string(nil), ids...)
+	}
+	return result
+}
+
+func (tc *threadCache) evictExpired(now time.Time) {
+	for elem := tc.order.Front(); elem != nil && elem.Value.(*thread).expires.Before(now); elem = tc.order.Front() {
+		tc.remove(elem)
+	}
+}
+
+func (tc *threadCache) remove(elem *list.Element) {
+	tc.order.Remove(elem)
+	t := elem.Value.(*thread)
+	delete(tc.entries, t.original)
+	for channel, ids := range t.copies {
+		for _, id := range ids {
+			delete(tc.entries, messageKey{channel: channel, id: id})
+		}
+	}
+}
+
+// stripMention removes mention of author which platforms put at the beginning of replies.
+func stripMention(text, author string) string {
+	if author == "" {
+		return text
+	}
+	mention := "@" + author
+	if len(text) < len(mention) || !strings.EqualFold(text[:len(mention)], mention) {
+		return text
+	}
+	rest := text[len(mention):]
+	if rest != "" && !unicode.IsSpace(rune(rest[0])) && rest[0] != ',' {
+		return text
+	}
+	return strings.TrimLeftFunc(strings.TrimPrefix(rest, ","), unicode.IsSpace)
+}
@@ -0,0 +1,49 @@
+package chat
+
+import "testing"
+
+func TestStripMention(t *testing.T) {
+	tests := []struct {
+		text   string
+		author string
+		want   string
+	}{
+		{text: "@alice yes", author: "alice", want: "yes"},
+		{text: "@Alice, yes", author: "alice", want: "yes"},
+		{text: "@alice", author: "alice", want: ""},
+		{text: "@alicebob yes", author: "alice", want: "@alicebob yes"},
+		{text: "yes @alice", author: "alice", want: "yes @alice"},
+		{text: "@alice yes", author: "", want: "@alice yes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := stripMention(tt.text, tt.author); got != tt.want {
+				t.Errorf("stripMention(%q, %q) = %q, want %q", tt.text, tt.author, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThreadCacheLookup(t *testing.T) {
+	cache := newThreadCache(10, threadCacheTTL)
+	original := messageKey{channel: testTwitch, id: "1"}
+	cache.Add(original, "alice")
+	cache.AddCopy(original, testVk, "10")
+	cache.AddCopy(original, testVk, "11")
+
+	for _, key := range []messageKey{original, {channel: testVk, id: "10"}} {
+		got, ok := cache.Lookup(key)
+		if !ok {
+			t.Fatalf("Lookup(%v) found nothing", key)
+		}
+		if got.author != "alice" {
+			t.Errorf("Lookup(%v) author = %q, want %q", key, got.author, "alice")
+		}
+		if id, _ := got.idIn(testVk); id != "10" {
+			t.Errorf("Lookup(%v) copy in %v = %q, want the first part %q", key, testVk, id, "10")
+		}
+	}
+	if _, ok := cache.Lookup(messageKey{channel: testTelegram, id: "10"}); ok {
+		t.Errorf("Lookup() found thread by ID of other channel")
+	}
+}
@@ -1,65 +1,506 @@
 package chat
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/MrMamka/combchats/pkg/twitchapi"
 	"github.com/gempir/go-twitch-irc/v4"
 )
 
+// twitchChannelURL is the prefix of channel pages, it is followed by login.
+const twitchChannelURL = "https://www.twitch.tv/"
+
 type TwitchChat struct {
+	health
 	channelName string
 	client      *twitch.Client
+	stop        *stopSignal
 }
 
 func NewTwitchChat(channelName string) *TwitchChat {
+	client := twitch.NewAnonymousClient()
+	client.Join(channelName)
 	return &TwitchChat{
 		channelName: channelName,
+		client:      client,
+		stop:        newStopSignal(),
 	}
 }
 
 func (tc *TwitchChat) Start(output chan<- Message) {
-	go func() {
-		tc.client = twitch.NewAnonymousClient()
+	tc.client.OnPrivateMessage(func(msg twitch.PrivateMessage) {
+		result := Message{
+			ID:        msg.ID,
+			Text:      msg.Message,
+			Author:    msg.User.DisplayName,
+			AuthorURL: twitchChannelURL + msg.User.Name,
+			Time:      msg.Time,
+			Roles:     twitchRoles(msg.User),
+			Emotes:    twitchEmotes(msg.Message, msg.Emotes),
+		}
+		if msg.Bits > 0 {
+			result.Event = Event{Type: CheerEvent, Bits: msg.Bits}
+		}
+		if msg.Reply != nil {
+			result.ReplyTo = msg.Reply.ParentMsgID
+			result.ReplyAuthor = msg.Reply.ParentDisplayName
+		}
+		tc.stop.send(output, result)
+	})
+
+	tc.client.OnUserNoticeMessage(func(msg twitch.UserNoticeMessage) {
+		event, ok := userNoticeEvent(msg)
+		if !ok {
+			return
+		}
+		tc.stop.send(output, Message{ID: msg.ID, Text: msg.Message, Author: msg.User.DisplayName, AuthorURL: twitchChannelURL + msg.User.Name,
+			Time: msg.Time, Event: event, Roles: twitchRoles(msg.User)})
+	})
 
-		tc.client.OnPrivateMessage(func(msg twitch.PrivateMessage) { // TODO: обрабатывать, когда сообщение - ответ на другое?
-			output <- Message{Text: msg.Message, Author: msg.User.DisplayName, Time: msg.Time}
+	tc.client.OnClearChatMessage(func(msg twitch.ClearChatMessage) {
+		event := Event{Type: ClearChatEvent}
+		if msg.TargetUsername != "" {
+			event = Event{
+				Type:       BanEvent,
+				TargetUser: msg.TargetUsername,
+				Duration:   time.Duration(msg.BanDuration) * time.Second,
+			}
+		}
+		tc.stop.send(output, Message{Time: msg.Time, Event: event})
+	})
+
+	// Text of the deleted message is dropped, repeating it elsewhere would undo the moderation.
+	tc.client.OnClearMessage(func(msg twitch.ClearMessage) {
+		tc.stop.send(output, Message{
+			Time:  time.Now(),
+			Event: Event{Type: DeleteMessageEvent, TargetUser: msg.Login, TargetID: msg.TargetMsgID},
 		})
+	})
 
-		tc.client.Join(tc.channelName)
+	// Stop called before the client connected can't disconnect it, so the client is disconnected here.
+	tc.client.OnConnect(func() {
+		if tc.stop.stopped() {
+			tc.client.Disconnect()
+			return
+		}
+		tc.connected()
+	})
+	tc.client.OnReconnectMessage(func(twitch.ReconnectMessage) {
+		tc.disconnected(nil)
+	})
 
+	go func() {
 		err := tc.client.Connect()
+		if errors.Is(err, twitch.ErrClientDisconnected) {
+			err = nil
+		}
+		tc.disconnected(err)
 		if err != nil {
 			fmt.Printf("error in connect: %v\n", err)
 		}
 	}()
 }
 
+// twitchEmotes converts emote positions in characters to byte offsets in text.
+func twitchEmotes(text string, emotes []*twitch.Emote) []Emote {
+	var offsets []int
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(text))
+
+	var result []Emote
+	for _, emote := range emotes {
+		for _, position := range emote.Positions {
+			if position.Start < 0 || position.End+1 >= len(offsets) || position.Start > position.End {
+				continue
+			}
+			result = append(result, Emote{Name: emote.Name, Start: offsets[position.Start], End: offsets[position.End+1]})
+		}
+	}
+	return result
+}
+
+func twitchRoles(user twitch.User) []Role {
+	var roles []Role
+	for _, role := range []Role{BroadcasterRole, ModeratorRole, VIPRole, SubscriberRole} {
+		if _, ok := user.Badges[string(role)]; ok {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// userNoticeEvent converts USERNOTICE to event. Returns false for notices which are not supported.
+func userNoticeEvent(msg twitch.UserNoticeMessage) (Event, bool) {
+	param := func(name string) string {
+		return msg.MsgParams["msg-param-"+name]
+	}
+	intParam := func(name string) int {
+		value, _ := strconv.Atoi(param(name))
+		return value
+	}
+
+	switch msg.MsgID {
+	case "sub":
+		return Event{Type: SubEvent, Plan: param("sub-plan"), Months: 1}, true
+	case "resub":
+		return Event{Type: ResubEvent, Plan: param("sub-plan"), Months: intParam("cumulative-months")}, true
+	case "subgift", "anonsubgift":
+		return Event{Type: SubGiftEvent, Plan: param("sub-plan"), Recipient: param("recipient-display-name")}, true
+	case "submysterygift", "anonsubmysterygift":
+		return Event{Type: MassSubGiftEvent, Plan: param("sub-plan"), Count: intParam("mass-gift-count")}, true
+	case "raid":
+		return Event{Type: RaidEvent, Viewers: intParam("viewerCount")}, true
+	case "announcement":
+		return Event{Type: AnnouncementEvent}, true
+	default:
+		return Event{}, false
+	}
+}
+
 func (tc *TwitchChat) Stop() {
-	print("here stop\n")
+	tc.stop.signal()
 	tc.client.Disconnect()
 }
 
+var (
+	ErrTwitchLoginTimeout = errors.New("twitch login timed out")
+	ErrTwitchNotConnected = errors.New("twitch sender is not connected")
+	ErrTwitchNotConfirmed = errors.New("twitch didn't confirm the message")
+	ErrTwitchRateLimited  = errors.New("twitch rejected the message: rate limit exceeded")
+	ErrTwitchBanned       = errors.New("twitch rejected the message: sender is banned in the channel")
+	ErrTwitchDuplicate    = errors.New("twitch rejected the message: it is identical to the previous one")
+	ErrTwitchSlowMode     = errors.New("twitch rejected the message: channel is in slow mode")
+)
+
+// twitchNoticeErrors maps msg-id of NOTICE sent in reply to PRIVMSG to error.
+var twitchNoticeErrors = map[string]error{
+	"msg_ratelimit": ErrTwitchRateLimited,
+	"msg_banned":    ErrTwitchBanned,
+	"msg_duplicate": ErrTwitchDuplicate,
+	"msg_slowmode":  ErrTwitchSlowMode,
+}
+
+const (
+	twitchLoginTimeout = 15 * time.Second
+	twitchSendTimeout  = 5 * time.Second
+	// twitchLateReplyTimeout is how long a reply to timed out message is waited for, so it isn't taken
+	// for the reply to a later message.
+	twitchLateReplyTimeout = 30 * time.Second
+	twitchStopTimeout      = 5 * time.Second
+
+	// Twitch allows 20 messages per 30 seconds to regular users and 100 to moderators and broadcaster.
+	// Regular users also can't send more than one message per second to a channel.
+	twitchRateWindow    = 30 * time.Second
+	twitchUserRate      = 20
+	twitchModeratorRate = 100
+	twitchUserInterval  = time.Second
+)
+
+// TwitchSender sends messages to twitch channel. Message is considered delivered when twitch replies
+// with USERSTATE, NOTICE in reply is converted to error.
 type TwitchSender struct {
 	client  *twitch.Client
 	channel string
+	// api is used for moderation, which is not available in chat.
+	api *twitchapi.Client
+
+	// sendMu serializes Send, so reply of twitch belongs to the only pending message.
+	sendMu sync.Mutex
+	sent   []time.Time
+
+	mu        sync.Mutex
+	joined    chan struct{}
+	roomID    string
+	moderator bool
+	pending   chan twitchReply
+	// unconfirmed are times of messages which got no reply in time. Twitch replies in order of messages,
+	// so the next replies belong to them, not to the pending message.
+	unconfirmed []time.Time
+	connErr     error
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewTwitchSender logs in as userName and joins the channel. It returns error if login fails.
+func NewTwitchSender(userName, channel, authToken string) (*TwitchSender, error) {
+	ts := &TwitchSender{
+		client:  twitch.NewClient(userName, "oauth:"+strings.TrimPrefix(authToken, "oauth:")),
+		channel: strings.ToLower(strings.TrimPrefix(channel, "#")),
+		api:     twitchapi.NewClient(authToken),
+		joined:  make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	ts.client.OnConnect(func() {
+		select {
+		case <-ts.stop:
+			// Sender was stopped while connecting, Disconnect works only after login.
+			go ts.client.Disconnect()
+		default:
+		}
+	})
+	ts.client.OnRoomStateMessage(func(msg twitch.RoomStateMessage) {
+		if strings.EqualFold(msg.Channel, ts.channel) {
+			ts.mu.Lock()
+			ts.roomID = msg.RoomID
+			ts.mu.Unlock()
+		}
+	})
+	ts.client.OnUserStateMessage(ts.onUserState)
+	ts.client.OnNoticeMessage(ts.onNotice)
+	ts.client.Join(ts.channel)
+
+	go func() {
+		err := ts.client.Connect()
+		ts.mu.Lock()
+		ts.connErr = err
+		ts.mu.Unlock()
+		close(ts.done)
+	}()
+
+	select {
+	case <-ts.joined:
+		return ts, nil
+	case <-ts.done:
+		return nil, fmt.Errorf("twitch login as %s: %w", userName, ts.connErr)
+	case <-time.After(twitchLoginTimeout):
+		ts.Stop()
+		return nil, ErrTwitchLoginTimeout
+	}
 }
 
-func NewTwitchSender(userName, channel, authToken string) *TwitchSender {
-	client := twitch.NewClient(userName, authToken)
+func (ts *TwitchSender) onUserState(msg twitch.UserStateMessage) {
+	if !strings.EqualFold(msg.Channel, ts.channel) {
+		return
+	}
+
+	_, isModerator := msg.User.Badges[string(ModeratorRole)]
+	_, isBroadcaster := msg.User.Badges[string(BroadcasterRole)]
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
 
-	go client.Connect() // TODO: передавать канал, чтобы убивать эту горутину
+	ts.moderator = isModerator || isBroadcaster
+	select {
+	case <-ts.joined:
+		ts.resolve(twitchReply{id: msg.Tags["id"]})
+	default:
+		// The first USERSTATE is the reply to JOIN.
+		close(ts.joined)
+	}
+}
 
-	return &TwitchSender{
-		client:  client,
-		channel: channel,
+func (ts *TwitchSender) onNotice(msg twitch.NoticeMessage) {
+	if !strings.EqualFold(msg.Channel, ts.channel) {
+		return
 	}
+
+	err, ok := twitchNoticeErrors[msg.MsgID]
+	if !ok {
+		err = fmt.Errorf("twitch notice %s: %s", msg.MsgID, msg.Message)
+	} else {
+		err = fmt.Errorf("%w: %s", err, msg.Message)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.resolve(twitchReply{err: err})
+}
+
+// twitchReply is the reply of twitch to PRIVMSG: id of created message or error from NOTICE.
+type twitchReply struct {
+	id  string
+	err error
+}
+
+// resolve reports reply to pending Send. Late replies to unconfirmed messages are dropped. Must be called with mu held.
+func (ts *TwitchSender) resolve(reply twitchReply) {
+	now := time.Now()
+	for len(ts.unconfirmed) > 0 && now.Sub(ts.unconfirmed[0]) > twitchLateReplyTimeout {
+		ts.unconfirmed = ts.unconfirmed[1:]
+	}
+	if len(ts.unconfirmed) > 0 {
+		ts.unconfirmed = ts.unconfirmed[1:]
+		return
+	}
+
+	if ts.pending == nil {
+		return
+	}
+	ts.pending <- reply
+	ts.pending = nil
 }
 
 func (ts *TwitchSender) Send(msg string) error {
-	ts.client.Say(ts.channel, msg)
-	return nil
+	_, err := ts.SendReply(msg, "")
+	return err
 }
 
-func (tc *TwitchSender) Stop() {
-	tc.client.Disconnect()
+// SendReply sends message as reply to message with id replyTo, or as usual message if replyTo is empty.
+// Returns id of the sent message.
+func (ts *TwitchSender) SendReply(msg, replyTo string) (string, error) {
+	ts.sendMu.Lock()
+	defer ts.sendMu.Unlock()
+
+	select {
+	case <-ts.done:
+		return "", fmt.Errorf("%w: %v", ErrTwitchNotConnected, ts.connectionError())
+	default:
+	}
+
+	if err := ts.waitRateLimit(); err != nil {
+		return "", err
+	}
+
+	result := make(chan twitchReply, 1)
+	ts.mu.Lock()
+	ts.pending = result
+	ts.mu.Unlock()
+	defer func() {
+		ts.mu.Lock()
+		ts.pending = nil
+		ts.mu.Unlock()
+	}()
+
+	if replyTo != "" {
+		ts.client.Reply(ts.channel, replyTo, msg)
+	} else {
+		ts.client.Say(ts.channel, msg)
+	}
+	sentAt := time.Now()
+	ts.sent = append(ts.sent, sentAt)
+
+	select {
+	case reply := <-result:
+		return reply.id, reply.err
+	case <-time.After(twitchSendTimeout):
+		ts.mu.Lock()
+		defer ts.mu.Unlock()
+		select {
+		case reply := <-result:
+			// Reply came together with the timeout.
+			return reply.id, reply.err
+		default:
+		}
+		ts.pending = nil
+		ts.unconfirmed = append(ts.unconfirmed, sentAt)
+		return "", ErrTwitchNotConfirmed
+	case <-ts.stop:
+		return "", ErrTwitchNotConnected
+	}
+}
+
+func (ts *TwitchSender) connectionError() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.connErr
+}
+
+// waitRateLimit waits until one more message can be sent without exceeding rate limits of twitch.
+func (ts *TwitchSender) waitRateLimit() error {
+	for {
+		delay := ts.rateLimitDelay(time.Now())
+		if delay <= 0 {
+			return nil
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ts.stop:
+			return ErrTwitchNotConnected
+		}
+	}
+}
+
+func (ts *TwitchSender) rateLimitDelay(now time.Time) time.Duration {
+	kept := ts.sent[:0]
+	for _, sentAt := range ts.sent {
+		if now.Sub(sentAt) < twitchRateWindow {
+			kept = append(kept, sentAt)
+		}
+	}
+	ts.sent = kept
+
+	ts.mu.Lock()
+	limit, interval := twitchUserRate, twitchUserInterval
+	if ts.moderator {
+		limit, interval = twitchModeratorRate, 0
+	}
+	ts.mu.Unlock()
+
+	var delay time.Duration
+	if len(ts.sent) >= limit {
+		delay = ts.sent[len(ts.sent)-limit].Add(twitchRateWindow).Sub(now)
+	}
+	if len(ts.sent) > 0 {
+		if intervalDelay := ts.sent[len(ts.sent)-1].Add(interval).Sub(now); intervalDelay > delay {
+			delay = intervalDelay
+		}
+	}
+	return delay
+}
+
+func (ts *TwitchSender) broadcasterID() (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.roomID == "" {
+		return "", errors.New("twitch channel id is not known yet")
+	}
+	return ts.roomID, nil
+}
+
+// Delete deletes message, sender account must be a moderator with moderator:manage:chat_messages scope of token.
+func (ts *TwitchSender) Delete(id string) error {
+	broadcasterID, err := ts.broadcasterID()
+	if err != nil {
+		return err
+	}
+	return ts.api.DeleteChatMessage(broadcasterID, id)
+}
+
+// Ban bans or times out user, sender account must be a moderator with moderator:manage:banned_users scope of token.
+func (ts *TwitchSender) Ban(user string, duration time.Duration) error {
+	broadcasterID, err := ts.broadcasterID()
+	if err != nil {
+		return err
+	}
+	userID, err := ts.api.UserID(user)
+	if err != nil {
+		return err
+	}
+	return ts.api.BanUser(broadcasterID, userID, duration, "")
+}
+
+// Sanitize neutralizes chat commands, which start with slash or dot and are executed if sender is a moderator.
+func (ts *TwitchSender) Sanitize(msg string) string {
+	return neutralizeCommand(stripInvisible(msg), "/.")
+}
+
+// Limits returns maximum length of twitch chat message.
+func (ts *TwitchSender) Limits() Limits {
+	return Limits{MaxRunes: 500}
+}
+
+// Stop disconnects from twitch and waits until connection is closed.
+func (ts *TwitchSender) Stop() {
+	ts.stopOnce.Do(func() {
+		close(ts.stop)
+		ts.client.Disconnect()
+	})
+
+	select {
+	case <-ts.done:
+	case <-time.After(twitchStopTimeout):
+		fmt.Printf("twitch sender for %s didn't stop in time\n", ts.channel)
+	}
 }
@@ -1,6 +1,8 @@
 package chat
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -8,49 +10,63 @@ import (
 )
 
 type VkChat struct {
+	health
 	channelName string
 	client      *vk.Client
-	stoped      bool
+	stop        *stopSignal
 }
 
 func NewVkChat(channelName string) *VkChat {
+	client := vk.NewAnonymousClient()
+	client.Join(channelName)
 	return &VkChat{
 		channelName: channelName,
+		client:      client,
+		stop:        newStopSignal(),
 	}
 }
 
 func (vc *VkChat) Start(output chan<- Message) {
-	go func() {
-		vc.client = vk.NewAnonymousClient()
-
-		vc.client.OnMessage(func(msg vk.Message) { // TODO: обрабатывать, когда сообщение - ответ на другое?
-			if vc.stoped {
-				return
-			}
-
-			var builder strings.Builder
-			for _, subj := range msg.Data {
-				builder.WriteString(subj.Content)
+	vc.client.OnMessage(func(msg vk.Message) {
+		var builder strings.Builder
+		var emotes []Emote
+		for _, subj := range msg.Data {
+			if subj.Type == vk.MessageSubjectTypeSmile {
+				emotes = append(emotes, Emote{Name: subj.Content, Start: builder.Len(), End: builder.Len() + len(subj.Content)})
 			}
+			builder.WriteString(subj.Content)
+		}
 
-			output <- Message{
-				Text:   builder.String(),
-				Author: msg.Author,
-				Time:   time.Unix(msg.Time, 0)}
-		})
+		result := Message{
+			ID:     strconv.Itoa(msg.ID),
+			Text:   builder.String(),
+			Author: msg.Author,
+			Time:   time.Unix(msg.Time, 0),
+			Emotes: emotes,
+		}
+		if msg.Parent != nil {
+			result.ReplyTo = strconv.Itoa(msg.Parent.ID)
+			result.ReplyAuthor = msg.Parent.Author
+		}
+		vc.stop.send(output, result)
+	})
+	vc.client.OnConnect(vc.connected)
 
-		vc.client.Join(vc.channelName)
-
-		vc.client.Connect()
-		// if err != nil {
-		// 	panic(err) // TODO: убрать панику и просто выбрасывать ошибку
-		// }
+	go func() {
+		err := vc.client.Connect()
+		if vc.stop.stopped() {
+			err = nil
+		}
+		vc.disconnected(err)
+		if err != nil {
+			fmt.Printf("error in vk connect: %v\n", err)
+		}
 	}()
 }
 
 func (vc *VkChat) Stop() {
+	vc.stop.signal()
 	vc.client.Disconnect()
-	vc.stoped = true
 }
 
 type VkSender struct {
@@ -66,7 +82,37 @@ func NewVkSender(channelName, authToken string) *VkSender {
 }
 
 func (vs *VkSender) Send(msg string) error {
-	return vs.client.SendMessage(msg)
+	_, err := vs.SendTracked(msg)
+	return err
+}
+
+func (vs *VkSender) SendTracked(msg string) (string, error) {
+	id, err := vs.client.SendMessage(msg)
+	if err != nil || id == 0 {
+		return "", err
+	}
+	return strconv.Itoa(id), nil
+}
+
+func (vs *VkSender) Delete(id string) error {
+	messageID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid vk message id %q", id)
+	}
+	return vs.client.DeleteMessage(messageID)
+}
+
+func (vs *VkSender) Ban(user string, duration time.Duration) error {
+	return ErrModerationNotSupported
+}
+
+// Sanitize neutralizes chat commands, which start with slash.
+func (vs *VkSender) Sanitize(msg string) string {
+	return neutralizeCommand(stripInvisible(msg), "/")
+}
+
+func (vs *VkSender) Limits() Limits {
+	return Limits{MaxRunes: 500}
 }
 
 func (vc *VkSender) Stop() {}
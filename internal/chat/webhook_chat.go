@@ -0,0 +1,252 @@
+package chat
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	WebhookPathPrefix  = "/webhook/"
+	webhookQueueSize   = 100
+	webhookMaxBodySize = 64 << 10
+
+	webhookReadTimeout  = 10 * time.Second
+	webhookWriteTimeout = 10 * time.Second
+	webhookIdleTimeout  = time.Minute
+)
+
+var (
+	ErrWebhooksDisabled = errors.New("webhook server is not started")
+	ErrWebhookAddress   = errors.New("webhook channel must be in format path?token=secret")
+)
+
+// WebhookServer accepts messages for WebhookChat by HTTP.
+//
+// Message is sent as POST to WebhookPathPrefix + path with header "Authorization: Bearer <token>" and JSON body:
+//
+//	{"author": "name", "text": "message", "time": "2006-01-02T15:04:05Z", "platform": "label"}
+//
+// Time and platform are optional.
+type WebhookServer struct {
+	mu      sync.RWMutex
+	started bool
+	chats   map[string]map[*WebhookChat]struct{}
+}
+
+// Webhooks is the server used by WebhookChat. It is started by the bot.
+var Webhooks = &WebhookServer{chats: make(map[string]map[*WebhookChat]struct{})}
+
+type webhookMessage struct {
+	Author   string     `json:"author"`
+	Text     string     `json:"text"`
+	Time     *time.Time `json:"time"`
+	Platform string     `json:"platform"`
+}
+
+// Listen opens addr for webhooks, the server counts as started from now on. Requests are served by Serve.
+func (ws *WebhookServer) Listen(addr string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	ws.mu.Lock()
+	ws.started = true
+	ws.mu.Unlock()
+	return listener, nil
+}
+
+// Serve serves webhooks on listener opened by Listen. It always returns non-nil error like http.Serve.
+func (ws *WebhookServer) Serve(listener net.Listener) error {
+	defer func() {
+		ws.mu.Lock()
+		ws.started = false
+		ws.mu.Unlock()
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle(WebhookPathPrefix, ws)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: webhookReadTimeout,
+		ReadTimeout:       webhookReadTimeout,
+		WriteTimeout:      webhookWriteTimeout,
+		IdleTimeout:       webhookIdleTimeout,
+	}
+	return server.Serve(listener)
+}
+
+func (ws *WebhookServer) Started() bool {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	return ws.started
+}
+
+func (ws *WebhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, WebhookPathPrefix), "/")
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	receivers, ok := ws.receivers(path, token)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if len(receivers) == 0 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, webhookMaxBodySize))
+	if err != nil {
+		http.Error(w, "unable to read body", http.StatusBadRequest)
+		return
+	}
+
+	var rawMsg webhookMessage
+	if err := json.Unmarshal(body, &rawMsg); err != nil {
+		http.Error(w, fmt.Sprintf("invalid json: %v", err), http.StatusBadRequest)
+		return
+	}
+	if rawMsg.Text == "" {
+		http.Error(w, "text is required", http.StatusBadRequest)
+		return
+	}
+
+	msg := Message{Text: rawMsg.Text, Author: rawMsg.Author, Time: time.Now(), Platform: rawMsg.Platform}
+	if rawMsg.Time != nil {
+		msg.Time = *rawMsg.Time
+	}
+
+	// Message is accepted if any chat took it, so a retry of the client doesn't duplicate it in others.
+	accepted := false
+	for _, wc := range receivers {
+		select {
+		case wc.queue <- msg:
+			accepted = true
+		default:
+		}
+	}
+	if !accepted {
+		http.Error(w, "too many messages", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// receivers returns chats of the path with the token. Returns false if the path has no chats at all.
+// Lock is not held while request is handled, so slow clients don't block chats being started or stopped.
+func (ws *WebhookServer) receivers(path, token string) ([]*WebhookChat, bool) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	chats, ok := ws.chats[path]
+	if !ok {
+		return nil, false
+	}
+	var receivers []*WebhookChat
+	for wc := range chats {
+		if subtle.ConstantTimeCompare([]byte(wc.token), []byte(token)) == 1 {
+			receivers = append(receivers, wc)
+		}
+	}
+	return receivers, true
+}
+
+func (ws *WebhookServer) register(wc *WebhookChat) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.chats[wc.path] == nil {
+		ws.chats[wc.path] = make(map[*WebhookChat]struct{})
+	}
+	ws.chats[wc.path][wc] = struct{}{}
+}
+
+func (ws *WebhookServer) unregister(wc *WebhookChat) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	delete(ws.chats[wc.path], wc)
+	if len(ws.chats[wc.path]) == 0 {
+		delete(ws.chats, wc.path)
+	}
+}
+
+// NewWebhookChannel creates webhook channel from address in format path?token=secret.
+// Path becomes name of the channel and token becomes its Secret, so the token is never shown.
+func NewWebhookChannel(address string) (Channel, error) {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return Channel{}, ErrWebhookAddress
+	}
+	path := strings.Trim(strings.TrimPrefix(parsed.Path, WebhookPathPrefix), "/")
+	token := parsed.Query().Get("token")
+	if path == "" || token == "" {
+		return Channel{}, ErrWebhookAddress
+	}
+	return Channel{Type: WebhookChannelType, Name: path, Secret: token}, nil
+}
+
+type WebhookChat struct {
+	health
+	path   string
+	token  string
+	server *WebhookServer
+	queue  chan Message
+	stop   chan struct{}
+}
+
+// NewWebhookChat creates chat receiving messages sent to path with token, see NewWebhookChannel.
+func NewWebhookChat(path, token string) (*WebhookChat, error) {
+	if !Webhooks.Started() {
+		return nil, ErrWebhooksDisabled
+	}
+	if path == "" || token == "" {
+		return nil, ErrWebhookAddress
+	}
+	return &WebhookChat{
+		path:   path,
+		token:  token,
+		server: Webhooks,
+		queue:  make(chan Message, webhookQueueSize),
+		stop:   make(chan struct{}),
+	}, nil
+}
+
+func (wc *WebhookChat) Start(output chan<- Message) {
+	wc.server.register(wc)
+	wc.connected()
+
+	go func() {
+		for {
+			select {
+			case msg := <-wc.queue:
+				select {
+				case output <- msg:
+				case <-wc.stop:
+					return
+				}
+			case <-wc.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (wc *WebhookChat) Stop() {
+	wc.server.unregister(wc)
+	wc.disconnected(nil)
+	close(wc.stop)
+}
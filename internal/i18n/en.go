@@ -0,0 +1,208 @@
+package i18n
+
+var en = map[string]string{
+	"language.name": "English",
+
+	// Channels input
+	"channels.no_name_after_platform": "No name after platform %s",
+	"channels.not_found":              "No chats found. Expected \"*platform* *name*\", \"*platform*:*name*\" or a link to a stream",
+	"channels.not_understood":         "Can't understand %q. Expected \"*platform* *name*\", \"*platform*:*name*\" or a link to a stream. Available platforms: %s",
+	"channels.no_name_in_url":         "Link %q has no channel name",
+	"channels.no_name":                "No name for %s",
+	"channels.bad_twitch_login":       "%q doesn't look like a Twitch login: it consists of 3-25 latin letters, digits and _",
+	"channels.unavailable":            "Can't connect %s: %v",
+
+	// Sessions
+	"stage.not_working":      "not started",
+	"stage.choose_mode":      "choosing mode",
+	"stage.pending_chats":    "entering chats",
+	"stage.combining":        "combining chats",
+	"stage.pending_routes":   "entering routes",
+	"stage.pending_tokens":   "entering accounts",
+	"stage.forwarding":       "forwarding messages",
+	"sessions.current_mark":  " (current)",
+	"sessions.chats":         "%d chat|%d chats",
+	"sessions.list":          "Sessions:\n%s\n\nCreate a new one - /new *name*, switch - /switch *name*, rename the current one - /rename *name*, close - /close *name*",
+	"sessions.one_word":      "Session name must be a single word",
+	"sessions.exists_switch": "Session %q already exists, switch to it - /switch %s",
+	"sessions.created":       "Session %q is created, other sessions keep working",
+	"sessions.switched":      "Current session: %q, %s",
+	"sessions.rename_usage":  "Write the new name after the command, e.g. /rename stream",
+	"sessions.exists":        "Session %q already exists",
+	"sessions.renamed":       "Session %q is renamed to %q",
+	"sessions.closed":        "Session %q is closed. Current session: %q, %s",
+	"sessions.name_usage":    "Write the session name after the command, e.g. /%s %s",
+	"sessions.not_found":     "There is no session %q. List of sessions - /sessions",
+
+	// Wizard
+	"wizard.cancel_button":     "✖️ Cancel",
+	"wizard.done_button":       "✅ Done",
+	"wizard.all_button":        "All to all",
+	"wizard.forwarding_button": "Forwarding",
+	"wizard.combining_button":  "Combining",
+	"wizard.no_chats":          "No chats are added yet.\n\n",
+	"wizard.chats":             "Added chats:\n%s\n\n",
+	"wizard.platform_chosen":   "Platform %s is chosen - send the streamer's name or the chat address. /help describes the address format",
+	"wizard.choose_platform":   "Choose a platform with the buttons below and send the streamer's name or the chat address, or just write \"*platform* *name*\". A chat added by mistake can be removed with the ❌ button. Finish with the «Done» button or the /done command",
+	"wizard.no_routes":         "none yet",
+	"wizard.routes":            "Chats:\n%s\n\nChoose where to forward messages: → from the left chat to the right one, ← from the right to the left, ↔ both ways, pressing again removes the route. Routes can also be written as text: \"1>2\", \"1<2\" or \"1<>2\". Finish with the «Done» button or the /done command.\n\nRoutes: %s",
+	"wizard.outdated":          "This message is outdated",
+	"wizard.cancelled":         "Setup is cancelled",
+
+	// Forwarding
+	"start.starting":               "Starting...",
+	"start.done":                   "Done!",
+	"wizard.added":                 "Saved:\n%s\n\n%s",
+	"wizard.duplicate":             "%s is already in the list",
+	"forwarding.need_two_chats":    "Forwarding needs at least two chats. Enter one more chat",
+	"forwarding.starting":          "Forwarding is still starting, please wait. Stop - /stop",
+	"forwarding.stopped":           "Forwarding is stopped. %d own message of the bot was not forwarded|Forwarding is stopped. %d own messages of the bot were not forwarded",
+	"forwarding.commands":          "To stop forwarding write /stop. Change message format - /template, handling of long messages - /long, repeating moderation - /moderation, text cleanup - /raw, chats state - /status",
+	"forwarding.start_failed":      "Failed to start forwarding: %v. Start over with /restart",
+	"combining.start_failed":       "Failed to start the chat: %v. Start over with /restart",
+	"combining.starting":           "The chat is still starting, please wait. Stop - /stop",
+	"routes.already_added":         "These routes are already saved",
+	"routes.none":                  "No routes are set. Choose a route with a button, enter it, e.g. \"1>2\", or /all",
+	"routes.bad_format":            "Invalid route format %q. Expected \"1>2\", \"1<2\" or \"1<>2\"",
+	"routes.same_chat":             "Route %q leads from a chat to itself",
+	"routes.to_webhook":            "Messages can't be forwarded to %s, Webhook can only be a source",
+	"routes.bad_position":          "There is no chat number %q. Chat numbers are from 1 to %d",
+	"routes.set_all":               "For all routes: %s",
+	"routes.set_selected":          "For the selected routes: %s",
+	"credentials.checking":         "Checking...",
+	"credentials.saved":            "Saved, messages to %s will be sent as %s",
+	"credentials.unverified":       "Saved, but the token for %s couldn't be verified: the platform answered in an unknown format. If the token is wrong, messages won't be sent",
+	"credentials.bad_format":       "Invalid input format. Expected %s",
+	"credentials.name_format":      "\"*name*\" or \"*name* *password*\"",
+	"credentials.token_format":     "\"*token*\" or \"*name* *token*\"",
+	"credentials.account_mismatch": "The token belongs to account %s, not %s. Enter the token of the right account or only the token without a name",
+	"credentials.invalid_token":    "%s doesn't accept this token: it is invalid or expired. Check that the token is copied completely, or get a new one",
+	"credentials.missing_scope":    "The token has no chat:edit scope, it can't write to chat without it. Get a token with this scope, e.g. at twitchtokengenerator.com",
+	"credentials.no_token":         "This platform needs a token. Expected %s",
+	"credentials.verify_failed":    "Failed to check the account: %v. Try again",
+	"credentials.request_name":     "Enter the nick to send messages to %s from, and its password if the nick is registered. In format %s",
+	"credentials.request_token":    "Enter the %s token of the account to send messages to %s from. The bot learns the account name from the token. The bot deletes the message with the token right away. /help tells where to get a token",
+	"long.truncate":                "truncate (cut with an ellipsis)",
+	"long.split":                   "split (send as several messages)",
+	"long.summary":                 "Chats:\n%s\n\nLong messages:\n%s\n\nTo change handling for all routes write /long truncate or /long split, for some routes - /long *routes* truncate|split, e.g. /long 1>2 split",
+	"long.unknown":                 "Unknown mode. Available: truncate, split",
+
+	// Templates
+	"help.template":           "Template fields: {platform} - platform, {channel} - channel, {author} - author, {text} - text (required), {time} - time, {badges} - badges of author roles. For example: [{platform}/{channel}] {badges}{author}: {text}",
+	"template.sample_text":    "Hello!",
+	"template.current":        "Current template: %s\nExample: %s\nTo change it write /template *template*. %s",
+	"template.invalid":        "Invalid template: %v. %s",
+	"template.changed":        "Template is changed. Example: %s",
+	"template.route":          "%s → %s: %s\nExample: %s",
+	"template.routes":         "Chats:\n%s\n\nRoute templates:\n%s\n\nTo change the template of all routes write /template *template*, for some routes - /template *routes* *template*, e.g. /template 1>2,2<>3 {author}: {text}. %s",
+	"template.changed_all":    "Template of all routes is changed. Example: %s",
+	"template.changed_routes": "Template of the routes is changed. Example: %s",
+	"routes.missing":          "Route %s → %s is not forwarded",
+
+	// Moderation and raw text
+	"moderation.off":     "off (don't repeat)",
+	"moderation.delete":  "delete (delete copies)",
+	"moderation.ban":     "ban (delete copies and ban)",
+	"moderation.summary": "Chats:\n%s\n\nModeration:\n%s\n\nModes: off - don't repeat moderation, delete - delete forwarded copies of messages deleted in the source chat and of messages of users banned there, ban - also ban the user with the same name in the destination chat. The sender account must be a moderator of the destination chat, on Twitch the token needs moderator:manage:chat_messages and moderator:manage:banned_users scopes.\n\nTo change the mode for all routes write /moderation *mode*, for some routes - /moderation *routes* *mode*, e.g. /moderation 1>2 delete",
+	"moderation.unknown": "Unknown mode. Available: off, delete, ban",
+	"moderation.set":     "Moderation for the selected routes: %s",
+	"raw.on":             "on (text as is)",
+	"raw.off":            "off (text is cleaned up)",
+	"raw.summary":        "Chats:\n%s\n\nForwarding without cleanup:\n%s\n\nBy default forwarded text is cleaned up: platform commands (e.g. /ban on Twitch) are neutralized, invisible characters are removed and the author name can't pretend to be another message. Mode on sends text as is - use it only if you trust all authors of the source chat.\n\nTo change the mode for all routes write /raw on or /raw off, for some routes - /raw *routes* on|off, e.g. /raw 1>2 on",
+	"raw.unknown":        "Unknown mode. Available: on, off",
+
+	// Filter and emotes
+	"filter.help":         "Filter rules:\nallow-author *names* - pass only messages of these authors\ndeny-author *names* - don't pass messages of these authors (e.g. deny-author nightbot streamelements)\ninclude *regexp* - pass only messages matching the regular expression\nexclude *regexp* - don't pass messages matching the regular expression\nignore-prefix *prefixes* - don't pass messages starting with the prefixes (e.g. ignore-prefix !)\nmin-length *n*, max-length *n* - don't pass messages shorter or longer than n characters\nrequire-role *roles*, deny-role *roles* - pass only messages of authors with the roles or, on the contrary, don't pass them. Roles: broadcaster, moderator, vip, subscriber\nCommands: /filter add *rule*, /filter remove *number*, /filter clear",
+	"filter.invalid_rule": "Invalid rule: %v\n\n%s",
+	"filter.added":        "Rule is added.\n%s",
+	"filter.no_rule":      "There is no rule with this number. List of rules: /filter",
+	"filter.removed":      "Rule is removed.\n%s",
+	"filter.cleared":      "All rules are removed",
+	"filter.unknown":      "Unknown filter command.\n\n%s",
+	"filter.empty":        "Filter is empty, all messages pass",
+	"filter.rules":        "Filter rules:\n%s",
+	"emotes.help":         "When forwarding, emotes are replaced by emotes of the destination platform, or by emoji if it has none.\nCommands: /emotes add *platform*=*emote* ... emoji=*emoji* (e.g. /emotes add twitch=Kappa vk=kappa emoji=😏), /emotes remove *emote*",
+	"emotes.invalid":      "Invalid mapping: %v\n\n%s",
+	"emotes.saved":        "Saved: %s",
+	"emotes.not_found":    "There is no such emote in the table. List: /emotes",
+	"emotes.removed":      "Emote is removed from the table",
+	"emotes.unknown":      "Unknown command.\n\n%s",
+	"emotes.empty":        "Emote table is empty",
+	"emotes.table":        "Emote table:\n%s",
+
+	// Status and combining
+	"status.connecting":       "connecting",
+	"status.connected":        "connected",
+	"status.disconnected":     "disconnected",
+	"status.uptime":           "Session is running for %s\n\nChats:",
+	"status.receives_only":    "only receives forwarded messages",
+	"status.routes":           "\n\nRoutes:",
+	"status.route":            "\n%s → %s: sent %d, failed %d, not forwarded %d",
+	"status.messages":         "%d message|%d messages",
+	"status.last_message":     "last one %s ago",
+	"status.reconnects":       "%d reconnect|%d reconnects",
+	"status.last_error":       "last error: %v",
+	"duration.hours":          "%d h %d min",
+	"duration.minutes":        "%d min %d s",
+	"duration.seconds":        "%d s",
+	"combining.add_usage":     "Write chats after the command, e.g. /add twitch foo or /add https://live.vkplay.ru/bar",
+	"combining.already_added": "%s is already connected",
+	"combining.add_failed":    "Failed to connect %s: %v",
+	"combining.added":         "Connected %s",
+	"combining.remove_usage":  "Write numbers or chats after the command, e.g. /remove 2 or /remove twitch foo. Chats:\n%s",
+	"combining.not_added":     "%s is not connected",
+	"combining.removed":       "Disconnected %s",
+	"combining.all_removed":   "No chats are connected anymore, add a new one with /add",
+	"combining.no_chats":      "No chats are connected. Add a chat with /add",
+	"combining.list":          "Connected chats:\n%s",
+
+	// Stored credentials and restarts
+	"credentials.none":            "The bot keeps no tokens of this chat",
+	"credentials.item":            "%d. %s as %s, token %s (session %q)",
+	"credentials.list":            "Saved tokens:\n%s\n\nTo delete a token write /revoke *number*. The session using it will be stopped",
+	"credentials.revoke_usage":    "Write the number of the token from the /credentials list, e.g. /revoke 1",
+	"credentials.revoked":         "Token of %s is deleted from the bot. It stays valid on the platform, it can be revoked in the account settings",
+	"credentials.session_stopped": ". Session %q is stopped",
+	"credentials.delete_failed":   "Failed to delete the message with the token, please delete it yourself",
+	"resume.working":              "The bot was restarted, resuming session %q",
+	"resume.setup":                "The bot was restarted. Setup of session %q continues from the same step, /help tells what to enter",
+
+	// Events
+	"event.cheer":          "%[2]s cheered %[1]d bit|%[2]s cheered %[1]d bits",
+	"event.announcement":   "Announcement from %s",
+	"event.sub":            "%s subscribed (%s)",
+	"event.resub":          "%[2]s has been subscribed for %[1]d month (%[3]s)|%[2]s has been subscribed for %[1]d months (%[3]s)",
+	"event.sub_gift":       "%s gifted a sub to %s (%s)",
+	"event.mass_sub_gift":  "%[2]s is gifting %[1]d sub to viewers (%[3]s)|%[2]s is gifting %[1]d subs to viewers (%[3]s)",
+	"event.raid":           "Raid from %[2]s with %[1]d viewer|Raid from %[2]s with %[1]d viewers",
+	"event.timeout":        "%s is timed out for %s",
+	"event.ban":            "%s is banned",
+	"event.clear_chat":     "Chat is cleared by a moderator",
+	"event.delete_message": "Message of %s is deleted by a moderator",
+
+	// Help
+	"help.not_working":    "The bot combines chats of streams. Supported platforms are Vk Play Live, Twitch and Telegram groups",
+	"help.choose_mode":    "In combining mode messages appear in this telegram chat. In forwarding mode messages are sent from some stream chats to others. The /filter command sets which messages pass (e.g. to remove bots and !commands), the /emotes command sets how emotes are translated between platforms",
+	"help.pending_chats":  "The exact name of the streamer can be found in the URL of the stream. It's easier to choose the platform with a button. The platform can be written in any case and in Russian (Твич, Вк), and a link to the stream can be pasted instead of platform and name. Several chats can be listed in one message separated by commas or line breaks.",
+	"help.telegram_group": "For a Telegram group enter its @username or numeric id instead of a name. The bot must be a member of the group and see all messages (privacy mode is off). For IRC enter the server and the channel, e.g. irc.libera.chat/#channel (ircs:// or irc:// at the start chooses TLS or plain connection, TLS by default). For Matrix enter the room (#alias:server or !id:server), the homeserver address can precede it: https://matrix.example.org/#room:example.org. For Webhook enter the path and the secret in format path?token=secret, then messages can be sent with a POST request to /webhook/path with header \"Authorization: Bearer secret\" and JSON body {\"author\": ..., \"text\": ..., \"platform\": ...}. Webhook can only be a source of messages",
+	"help.combining":      "To stop the messages write /stop or /restart. /add *chats* connects new chats without interrupting others, /remove *numbers or chats* disconnects them, /list shows connected ones. /status shows the connection state of every chat and the time of the last message. /template shows and changes the message format, /links on|off turns on links to authors' channels.",
+	"help.pending_routes": "It's easier to choose routes with the buttons under the message with the list of chats. Route \"1>2\" forwards messages from the first chat to the second one, \"1<2\" - from the second to the first, \"1<>2\" - both ways. Chat numbers are in the list above. /all enables forwarding between all chats, /done finishes entering routes",
+	"help.pending_tokens": "The name can be found in the URL of your channel.\nA Vk token can be found after logging in to vk play live in the developer console, in the Cookie header of one of the requests. It goes after accessToken. Token example:\n7a41109f60fbb5aa16dcb4c4d3ea4a3ffac4af1d22aa8998b8a0209d0231faba (this token is not real)\nA Twitch token can be generated on special sites, e.g. twitchtokengenerator.com. Token example:\noauth:uy1tkpc8fer0xbh122ewrmq1cked2b (this token is not real)\nThe Twitch token must have the chat:edit scope.\nFor Matrix enter the access token of the account (it can be found in the Element client settings in \"Help & About\").\nFor Twitch, Vk and Matrix the token alone is enough: the bot checks it and learns the account name itself. If you enter \"*name* *token*\", the bot checks that the token belongs to this account.\nFor IRC enter the nick and its password if the nick is registered. By default the password is used for SASL, ?auth=nickserv or ?auth=pass in the channel address switch to NickServ IDENTIFY or server password\nMessages written from these accounts in the linked chats are not forwarded, so it's better to use separate accounts for the bot.",
+	"help.forwarding":     "To stop forwarding write /stop or /restart. /template shows and changes the message format for all or some routes. /long shows and changes what to do with messages longer than the platform limit: cut them (truncate) or send in parts (split). /moderation enables deleting forwarded copies of deleted messages and repeating bans on routes. /raw disables cleanup of platform commands and invisible characters in forwarded text on routes. /status shows the connection state of every chat and how many messages were forwarded, failed and dropped on every route.",
+	"help.sessions":       "One chat can have several sessions, e.g. combining and forwarding at the same time. Commands apply to the current session. /new *name* creates a session, /switch *name* changes the current one, /sessions shows the list, /rename *name* renames the current one, /close *name* stops and removes a session. /credentials shows tokens the bot keeps for sessions of this chat, /revoke *number* deletes a token. /language *ru|en* changes the language of the bot in this chat",
+	"mode.choose":         "Choose how to use the bot: forwarding messages (/forwarding) or combining chats (/combining)",
+	"mode.unsupported":    "Unsupported mode. Choose /forwarding or /combining",
+	"chats.none":          "No chats are added",
+	"combining.stopped":   "Chat is stopped.",
+	"combining.commands":  "To stop the chat write /stop. Change message format - /template, connect a chat - /add, disconnect - /remove, see connected ones - /list, chats state - /status, links to authors - /links",
+	"language.current":    "Language of the bot in this chat: %s. Available languages: %s. Change it - /language *code*",
+	"language.unknown":    "Language %q is not supported. Available languages: %s",
+	"language.changed":    "Language of the bot in this chat: %s",
+
+	// Links to authors
+	"links.on":       "Author names link to their channels. Turn off - /links off",
+	"links.off":      "Links to authors' channels are off. Turn on - /links on",
+	"links.enabled":  "Author names now link to their channels.",
+	"links.disabled": "Links to authors' channels are turned off.",
+	"links.usage":    "Use /links on or /links off",
+}
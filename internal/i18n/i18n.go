@@ -0,0 +1,106 @@
+// Package i18n keeps texts of the bot in supported languages.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Language string
+
+const (
+	Russian Language = "ru"
+	English Language = "en"
+
+	// Default is used for chats whose language is unknown. Texts missing in other catalogs are taken from it.
+	Default = Russian
+)
+
+// Languages are the supported languages in the order they are offered to users.
+var Languages = []Language{Russian, English}
+
+var catalogs = map[Language]map[string]string{
+	Russian: ru,
+	English: en,
+}
+
+// Parse returns the supported language of code, e.g. "en", "EN" or "en-US".
+func Parse(code string) (Language, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if base, _, ok := strings.Cut(code, "-"); ok {
+		code = base
+	}
+	lang := Language(code)
+	_, ok := catalogs[lang]
+	return lang, ok
+}
+
+// FromTelegram returns the language for language_code of a telegram user.
+// Users of unsupported languages get English, users without language_code get Default.
+func FromTelegram(code string) Language {
+	if code == "" {
+		return Default
+	}
+	if lang, ok := Parse(code); ok {
+		return lang
+	}
+	return English
+}
+
+// Name is the name of the language in itself.
+func (l Language) Name() string {
+	return l.T("language.name")
+}
+
+// T returns text of key formatted with args. Missing texts are taken from Default catalog, then key itself is returned.
+func (l Language) T(key string, args ...interface{}) string {
+	text := l.lookup(key)
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// N returns plural form of key for n formatted with n followed by args.
+// Forms of the text are separated by "|" in the order of pluralForm of the language.
+func (l Language) N(key string, n int, args ...interface{}) string {
+	forms := strings.Split(l.lookup(key), "|")
+	form := l.pluralForm(n)
+	if form >= len(forms) {
+		form = len(forms) - 1
+	}
+	return fmt.Sprintf(forms[form], append([]interface{}{n}, args...)...)
+}
+
+func (l Language) lookup(key string) string {
+	if text, ok := catalogs[l][key]; ok {
+		return text
+	}
+	if text, ok := catalogs[Default][key]; ok {
+		return text
+	}
+	return key
+}
+
+// pluralForm returns index of the plural form for n: one, few, many for Russian and one, other for English.
+func (l Language) pluralForm(n int) int {
+	if n < 0 {
+		n = -n
+	}
+	switch l {
+	case Russian:
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return 0
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return 1
+		default:
+			return 2
+		}
+	default:
+		if n == 1 {
+			return 0
+		}
+		return 1
+	}
+}
@@ -0,0 +1,125 @@
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestPluralForm(t *testing.T) {
+	tests := []struct {
+		lang Language
+		n    int
+		want int
+	}{
+		{Russian, 0, 2},
+		{Russian, 1, 0},
+		{Russian, 2, 1},
+		{Russian, 4, 1},
+		{Russian, 5, 2},
+		{Russian, 11, 2},
+		{Russian, 12, 2},
+		{Russian, 14, 2},
+		{Russian, 21, 0},
+		{Russian, 22, 1},
+		{Russian, 25, 2},
+		{Russian, 101, 0},
+		{Russian, 111, 2},
+		{Russian, 112, 2},
+		{Russian, 122, 1},
+		{Russian, -1, 0},
+		{English, 0, 1},
+		{English, 1, 0},
+		{English, 2, 1},
+		{English, 11, 1},
+		{English, 21, 1},
+		{English, -1, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.lang.pluralForm(tt.n); got != tt.want {
+			t.Errorf("%s.pluralForm(%d) = %d, want %d", tt.lang, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestN(t *testing.T) {
+	tests := []struct {
+		lang Language
+		n    int
+		want string
+	}{
+		{Russian, 1, "1 сообщение"},
+		{Russian, 3, "3 сообщения"},
+		{Russian, 11, "11 сообщений"},
+		{English, 1, "1 message"},
+		{English, 5, "5 messages"},
+	}
+	for _, tt := range tests {
+		if got := tt.lang.N("status.messages", tt.n); got != tt.want {
+			t.Errorf("%s.N(status.messages, %d) = %q, want %q", tt.lang, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	if got := Language("de").T("language.name"); got != Default.T("language.name") {
+		t.Errorf("text of unknown language = %q, want text of Default", got)
+	}
+	if got := English.T("no.such.key"); got != "no.such.key" {
+		t.Errorf("missing text = %q, want key", got)
+	}
+}
+
+func TestFromTelegram(t *testing.T) {
+	tests := []struct {
+		code string
+		want Language
+	}{
+		{"", Default},
+		{"ru", Russian},
+		{"en-US", English},
+		{"EN", English},
+		{"de", English},
+	}
+	for _, tt := range tests {
+		if got := FromTelegram(tt.code); got != tt.want {
+			t.Errorf("FromTelegram(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+var verbRegexp = regexp.MustCompile(`%(\[\d+\])?[-+# 0-9.]*[a-zA-Z%]`)
+
+// TestCatalogs checks that every text is translated and uses the same formatting verbs in both languages.
+// Verbs are compared as sets, because plural forms repeat them and their number differs between languages.
+func TestCatalogs(t *testing.T) {
+	verbs := func(text string) string {
+		set := make(map[string]bool)
+		for _, verb := range verbRegexp.FindAllString(text, -1) {
+			set[verb] = true
+		}
+		var result []string
+		for verb := range set {
+			result = append(result, verb)
+		}
+		sort.Strings(result)
+		return strings.Join(result, " ")
+	}
+
+	for key, ruText := range ru {
+		enText, ok := en[key]
+		if !ok {
+			t.Errorf("%s is missing in English catalog", key)
+			continue
+		}
+		if ruVerbs, enVerbs := verbs(ruText), verbs(enText); ruVerbs != enVerbs {
+			t.Errorf("%s has verbs %q in Russian and %q in English", key, ruVerbs, enVerbs)
+		}
+	}
+	for key := range en {
+		if _, ok := ru[key]; !ok {
+			t.Errorf("%s is missing in Russian catalog", key)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package i18n
+
+var ru = map[string]string{
+	"language.name": "Русский",
+
+	// Channels input
+	"channels.no_name_after_platform": "После площадки %s не указан ник",
+	"channels.not_found":              "Не найдено ни одного чата. Ожидалось \"*платформа* *ник*\", \"*платформа*:*ник*\" или ссылка на стрим",
+	"channels.not_understood":         "Не понял %q. Ожидалось \"*платформа* *ник*\", \"*платформа*:*ник*\" или ссылка на стрим. Доступные площадки: %s",
+	"channels.no_name_in_url":         "В ссылке %q нет имени канала",
+	"channels.no_name":                "Не указан ник для %s",
+	"channels.bad_twitch_login":       "%q не похоже на ник Twitch: он состоит из 3-25 латинских букв, цифр и _",
+	"channels.unavailable":            "Не получится подключить %s: %v",
+
+	// Sessions
+	"stage.not_working":      "не запущена",
+	"stage.choose_mode":      "выбор режима",
+	"stage.pending_chats":    "ввод чатов",
+	"stage.combining":        "объединяет чаты",
+	"stage.pending_routes":   "ввод маршрутов",
+	"stage.pending_tokens":   "ввод аккаунтов",
+	"stage.forwarding":       "пересылает сообщения",
+	"sessions.current_mark":  " (текущая)",
+	"sessions.chats":         "%d чат|%d чата|%d чатов",
+	"sessions.list":          "Сессии:\n%s\n\nСоздать новую - /new *имя*, переключиться - /switch *имя*, переименовать текущую - /rename *имя*, закрыть - /close *имя*",
+	"sessions.one_word":      "Имя сессии должно быть одним словом",
+	"sessions.exists_switch": "Сессия %q уже есть, переключиться на неё - /switch %s",
+	"sessions.created":       "Создана сессия %q, остальные сессии продолжают работать",
+	"sessions.switched":      "Текущая сессия: %q, %s",
+	"sessions.rename_usage":  "Напишите новое имя после команды, например /rename stream",
+	"sessions.exists":        "Сессия %q уже есть",
+	"sessions.renamed":       "Сессия %q переименована в %q",
+	"sessions.closed":        "Сессия %q закрыта. Текущая сессия: %q, %s",
+	"sessions.name_usage":    "Напишите имя сессии после команды, например /%s %s",
+	"sessions.not_found":     "Сессии %q нет. Список сессий - /sessions",
+
+	// Wizard
+	"wizard.cancel_button":     "✖️ Отмена",
+	"wizard.done_button":       "✅ Готово",
+	"wizard.all_button":        "Все со всеми",
+	"wizard.forwarding_button": "Пересылка",
+	"wizard.combining_button":  "Объединение",
+	"wizard.no_chats":          "Чаты ещё не добавлены.\n\n",
+	"wizard.chats":             "Добавленные чаты:\n%s\n\n",
+	"wizard.platform_chosen":   "Выбрана площадка %s - отправьте ник стримера или адрес чата. /help подскажет формат адреса",
+	"wizard.choose_platform":   "Выберите площадку кнопкой ниже и отправьте ник стримера или адрес чата, или сразу напишите \"*платформа* *ник*\". Лишний чат можно удалить кнопкой ❌. Конец ввода подтвердите кнопкой «Готово» или командой /done",
+	"wizard.no_routes":         "пока нет",
+	"wizard.routes":            "Чаты:\n%s\n\nВыберите, куда пересылать сообщения: → из левого чата в правый, ← из правого в левый, ↔ в обе стороны, повторное нажатие убирает маршрут. Маршруты можно также написать текстом: \"1>2\", \"1<2\" или \"1<>2\". Конец ввода подтвердите кнопкой «Готово» или командой /done.\n\nМаршруты: %s",
+	"wizard.outdated":          "Это сообщение устарело",
+	"wizard.cancelled":         "Настройка отменена",
+
+	// Forwarding
+	"start.starting":               "Запускаю...",
+	"start.done":                   "Готово!",
+	"wizard.added":                 "Записано:\n%s\n\n%s",
+	"wizard.duplicate":             "%s уже в списке",
+	"forwarding.need_two_chats":    "Для пересылки нужно хотя бы два чата. Введите ещё один чат",
+	"forwarding.starting":          "Пересылка ещё запускается, подождите немного. Остановить - /stop",
+	"forwarding.stopped":           "Пересылка остановлена. Не переслано собственных сообщений бота: %d",
+	"forwarding.commands":          "Если хотите остановить пересылку - напишите /stop. Изменить формат сообщений - /template, обработку длинных сообщений - /long, повторение модерации - /moderation, очистку текста - /raw, состояние чатов - /status",
+	"forwarding.start_failed":      "Не удалось запустить пересылку: %v. Начните заново с /restart",
+	"combining.start_failed":       "Не удалось запустить чат: %v. Начните заново с /restart",
+	"combining.starting":           "Чат ещё запускается, подождите немного. Остановить - /stop",
+	"routes.already_added":         "Эти маршруты уже записаны",
+	"routes.none":                  "Не задано ни одного маршрута. Выберите маршрут кнопкой, введите его, например \"1>2\", или /all",
+	"routes.bad_format":            "Неверный формат маршрута %q. Ожидалось \"1>2\", \"1<2\" или \"1<>2\"",
+	"routes.same_chat":             "Маршрут %q ведёт из чата в него же",
+	"routes.to_webhook":            "В %s нельзя пересылать сообщения, Webhook может быть только источником",
+	"routes.bad_position":          "Нет чата с номером %q. Номера чатов: от 1 до %d",
+	"routes.set_all":               "Для всех маршрутов: %s",
+	"routes.set_selected":          "Для выбранных маршрутов: %s",
+	"credentials.checking":         "Проверяю...",
+	"credentials.saved":            "Записано, сообщения в %s будут отправляться от %s",
+	"credentials.unverified":       "Записано, но проверить токен для %s не удалось: площадка ответила в непонятном формате. Если токен неверный, сообщения не будут отправляться",
+	"credentials.bad_format":       "Неверный формат ввода. Ожидалось %s",
+	"credentials.name_format":      "\"*имя*\" или \"*имя* *пароль*\"",
+	"credentials.token_format":     "\"*токен*\" или \"*имя* *токен*\"",
+	"credentials.account_mismatch": "Токен принадлежит аккаунту %s, а не %s. Введите токен нужного аккаунта или только токен без имени",
+	"credentials.invalid_token":    "%s не принимает этот токен: он неверный или истёк. Проверьте, что токен скопирован целиком, или получите новый",
+	"credentials.missing_scope":    "У токена нет права chat:edit, без него нельзя писать в чат. Получите токен с этим правом, например на twitchtokengenerator.com",
+	"credentials.no_token":         "Для этой площадки нужен токен. Ожидалось %s",
+	"credentials.verify_failed":    "Не удалось проверить аккаунт: %v. Попробуйте ещё раз",
+	"credentials.request_name":     "Введите ник, с которого будут отправляться сообщения в %s, и пароль, если ник зарегистрирован. В формате %s",
+	"credentials.request_token":    "Введите %s токен от аккаунта, с которого будут отправляться сообщения в %s. Имя аккаунта бот узнает по токену. Сообщение с токеном бот сразу удалит. /help подскажет, где взять токен",
+	"long.truncate":                "truncate (обрезать с многоточием)",
+	"long.split":                   "split (разбить на несколько сообщений)",
+	"long.summary":                 "Чаты:\n%s\n\nДлинные сообщения:\n%s\n\nЧтобы изменить обработку для всех маршрутов, напишите /long truncate или /long split, для отдельных маршрутов - /long *маршруты* truncate|split, например /long 1>2 split",
+	"long.unknown":                 "Неизвестный режим. Доступные: truncate, split",
+
+	// Templates
+	"help.template":           "Поля шаблона: {platform} - площадка, {channel} - канал, {author} - автор, {text} - текст (обязательно), {time} - время, {badges} - значки ролей автора. Например: [{platform}/{channel}] {badges}{author}: {text}",
+	"template.sample_text":    "Привет!",
+	"template.current":        "Текущий шаблон: %s\nПример: %s\nЧтобы изменить его, напишите /template *шаблон*. %s",
+	"template.invalid":        "Неверный шаблон: %v. %s",
+	"template.changed":        "Шаблон изменён. Пример: %s",
+	"template.route":          "%s → %s: %s\nПример: %s",
+	"template.routes":         "Чаты:\n%s\n\nШаблоны маршрутов:\n%s\n\nЧтобы изменить шаблон всех маршрутов, напишите /template *шаблон*, для отдельных маршрутов - /template *маршруты* *шаблон*, например /template 1>2,2<>3 {author}: {text}. %s",
+	"template.changed_all":    "Шаблон всех маршрутов изменён. Пример: %s",
+	"template.changed_routes": "Шаблон маршрутов изменён. Пример: %s",
+	"routes.missing":          "Маршрута %s → %s нет в пересылке",
+
+	// Moderation and raw text
+	"moderation.off":     "off (не повторять)",
+	"moderation.delete":  "delete (удалять копии)",
+	"moderation.ban":     "ban (удалять копии и банить)",
+	"moderation.summary": "Чаты:\n%s\n\nМодерация:\n%s\n\nРежимы: off - не повторять модерацию, delete - удалять пересланные копии сообщений, удалённых в исходном чате, и сообщений забаненных там пользователей, ban - ещё и банить пользователя с тем же именем в чате назначения. Аккаунт отправителя должен быть модератором чата назначения, для Twitch токену нужны права moderator:manage:chat_messages и moderator:manage:banned_users.\n\nЧтобы изменить режим для всех маршрутов, напишите /moderation *режим*, для отдельных маршрутов - /moderation *маршруты* *режим*, например /moderation 1>2 delete",
+	"moderation.unknown": "Неизвестный режим. Доступные: off, delete, ban",
+	"moderation.set":     "Модерация для выбранных маршрутов: %s",
+	"raw.on":             "on (текст как есть)",
+	"raw.off":            "off (текст очищается)",
+	"raw.summary":        "Чаты:\n%s\n\nПересылка без очистки:\n%s\n\nПо умолчанию пересылаемый текст очищается: команды площадок (например, /ban в Twitch) обезвреживаются, невидимые символы удаляются, а имя автора не может выдать себя за другое сообщение. Режим on отправляет текст как есть - используйте его, только если доверяете всем авторам исходного чата.\n\nЧтобы изменить режим для всех маршрутов, напишите /raw on или /raw off, для отдельных маршрутов - /raw *маршруты* on|off, например /raw 1>2 on",
+	"raw.unknown":        "Неизвестный режим. Доступные: on, off",
+
+	// Filter and emotes
+	"filter.help":         "Правила фильтра:\nallow-author *ники* - пропускать только сообщения этих авторов\ndeny-author *ники* - не пропускать сообщения этих авторов (например deny-author nightbot streamelements)\ninclude *regexp* - пропускать только сообщения, подходящие под регулярное выражение\nexclude *regexp* - не пропускать сообщения, подходящие под регулярное выражение\nignore-prefix *префиксы* - не пропускать сообщения, начинающиеся с префиксов (например ignore-prefix !)\nmin-length *n*, max-length *n* - не пропускать сообщения короче или длиннее n символов\nrequire-role *роли*, deny-role *роли* - пропускать только сообщения авторов с ролями или, наоборот, не пропускать их. Роли: broadcaster, moderator, vip, subscriber\nКоманды: /filter add *правило*, /filter remove *номер*, /filter clear",
+	"filter.invalid_rule": "Неверное правило: %v\n\n%s",
+	"filter.added":        "Правило добавлено.\n%s",
+	"filter.no_rule":      "Нет правила с таким номером. Список правил: /filter",
+	"filter.removed":      "Правило удалено.\n%s",
+	"filter.cleared":      "Все правила удалены",
+	"filter.unknown":      "Неизвестная команда фильтра.\n\n%s",
+	"filter.empty":        "Фильтр пуст, проходят все сообщения",
+	"filter.rules":        "Правила фильтра:\n%s",
+	"emotes.help":         "При пересылке эмоуты заменяются на эмоуты площадки, куда пересылается сообщение, а если их там нет - на эмодзи.\nКоманды: /emotes add *площадка*=*эмоут* ... emoji=*эмодзи* (например /emotes add twitch=Kappa vk=kappa emoji=😏), /emotes remove *эмоут*",
+	"emotes.invalid":      "Неверное соответствие: %v\n\n%s",
+	"emotes.saved":        "Записано: %s",
+	"emotes.not_found":    "Такого эмоута нет в таблице. Список: /emotes",
+	"emotes.removed":      "Эмоут удалён из таблицы",
+	"emotes.unknown":      "Неизвестная команда.\n\n%s",
+	"emotes.empty":        "Таблица эмоутов пуста",
+	"emotes.table":        "Таблица эмоутов:\n%s",
+
+	// Status and combining
+	"status.connecting":       "подключается",
+	"status.connected":        "подключён",
+	"status.disconnected":     "отключён",
+	"status.uptime":           "Сессия работает %s\n\nЧаты:",
+	"status.receives_only":    "только принимает пересланные сообщения",
+	"status.routes":           "\n\nМаршруты:",
+	"status.route":            "\n%s → %s: отправлено %d, ошибок %d, не переслано %d",
+	"status.messages":         "%d сообщение|%d сообщения|%d сообщений",
+	"status.last_message":     "последнее %s назад",
+	"status.reconnects":       "%d переподключение|%d переподключения|%d переподключений",
+	"status.last_error":       "последняя ошибка: %v",
+	"duration.hours":          "%d ч %d мин",
+	"duration.minutes":        "%d мин %d с",
+	"duration.seconds":        "%d с",
+	"combining.add_usage":     "Напишите чаты после команды, например /add twitch foo или /add https://live.vkplay.ru/bar",
+	"combining.already_added": "%s уже подключён",
+	"combining.add_failed":    "Не удалось подключить %s: %v",
+	"combining.added":         "Подключён %s",
+	"combining.remove_usage":  "Напишите номера или чаты после команды, например /remove 2 или /remove twitch foo. Чаты:\n%s",
+	"combining.not_added":     "%s не подключён",
+	"combining.removed":       "Отключён %s",
+	"combining.all_removed":   "Больше не подключено ни одного чата, добавьте новый с помощью /add",
+	"combining.no_chats":      "Не подключено ни одного чата. Добавьте чат с помощью /add",
+	"combining.list":          "Подключённые чаты:\n%s",
+
+	// Stored credentials and restarts
+	"credentials.none":            "Бот не хранит ни одного токена этого чата",
+	"credentials.item":            "%d. %s от %s, токен %s (сессия %q)",
+	"credentials.list":            "Сохранённые токены:\n%s\n\nЧтобы удалить токен, напишите /revoke *номер*. Сессия, которая его использует, будет остановлена",
+	"credentials.revoke_usage":    "Укажите номер токена из списка /credentials, например /revoke 1",
+	"credentials.revoked":         "Токен %s удалён из бота. На площадке он остаётся действительным, отозвать его можно в настройках аккаунта",
+	"credentials.session_stopped": ". Сессия %q остановлена",
+	"credentials.delete_failed":   "Не удалось удалить сообщение с токеном, удалите его, пожалуйста, сами",
+	"resume.working":              "Бот был перезапущен, возобновляю сессию %q",
+	"resume.setup":                "Бот был перезапущен. Настройка сессии %q продолжается с того же шага, /help подскажет, что ввести",
+
+	// Events
+	"event.cheer":          "%[2]s отправил %[1]d битс",
+	"event.announcement":   "Объявление от %s",
+	"event.sub":            "%s подписался (%s)",
+	"event.resub":          "%[2]s подписан уже %[1]d месяц (%[3]s)|%[2]s подписан уже %[1]d месяца (%[3]s)|%[2]s подписан уже %[1]d месяцев (%[3]s)",
+	"event.sub_gift":       "%s подарил подписку %s (%s)",
+	"event.mass_sub_gift":  "%[2]s дарит %[1]d подписку зрителям (%[3]s)|%[2]s дарит %[1]d подписки зрителям (%[3]s)|%[2]s дарит %[1]d подписок зрителям (%[3]s)",
+	"event.raid":           "Рейд от %[2]s на %[1]d зрителя|Рейд от %[2]s на %[1]d зрителей|Рейд от %[2]s на %[1]d зрителей",
+	"event.timeout":        "%s получил таймаут на %s",
+	"event.ban":            "%s забанен",
+	"event.clear_chat":     "Чат очищен модератором",
+	"event.delete_message": "Сообщение %s удалено модератором",
+
+	// Help
+	"help.not_working":    "Бот умеет объединять чаты стримов. В данный момент поддерживаются площадки: Vk Play Live, Twitch и группы Telegram",
+	"help.choose_mode":    "В режиме объединения сообщения будут появляться в этом телеграм чате. В режим пересылки сообщения будут отправляться из одних чатов стримов в другие. Командой /filter можно настроить, какие сообщения пропускать (например, убрать ботов и !команды), командой /emotes - как переводить эмоуты между площадками",
+	"help.pending_chats":  "Правильное написание ника стримера можно узнать в URL его стрима. Площадку удобнее выбрать кнопкой. Площадку можно написать в любом регистре и по-русски (Твич, Вк), а вместо площадки и ника - вставить ссылку на стрим. Несколько чатов можно перечислить в одном сообщении через запятую или с новой строки.",
+	"help.telegram_group": "Для группы Telegram вместо ника укажите её @username или числовой id. Бот должен состоять в группе и видеть все сообщения (режим приватности выключен). Для IRC вместо ника укажите сервер и канал, например irc.libera.chat/#channel (ircs:// или irc:// в начале выбирает TLS или обычное соединение, по умолчанию TLS). Для Matrix укажите комнату (#alias:server или !id:server), перед ней можно указать адрес homeserver'а: https://matrix.example.org/#room:example.org. Для Webhook укажите путь и секрет в формате path?token=secret, после этого сообщения можно присылать POST запросом на /webhook/path с заголовком \"Authorization: Bearer secret\" и JSON телом {\"author\": ..., \"text\": ..., \"platform\": ...}. Webhook может быть только источником сообщений",
+	"help.combining":      "Чтобы остановить поток сообщений напишите /stop или /restart. /add *чаты* подключает новые чаты, не прерывая остальные, /remove *номера или чаты* отключает их, /list показывает подключённые. /status показывает состояние подключения каждого чата и время последнего сообщения. /template показывает и меняет формат сообщений, /links on|off включает ссылки на каналы авторов.",
+	"help.pending_routes": "Маршруты удобнее выбрать кнопками под сообщением со списком чатов. Маршрут \"1>2\" пересылает сообщения из первого чата во второй, \"1<2\" - из второго в первый, \"1<>2\" - в обе стороны. Номера чатов указаны в списке выше. /all включает пересылку между всеми чатами, /done завершает ввод маршрутов",
+	"help.pending_tokens": "Ник можно узнать в URL, зайдя на свой канал.\nVk токен можно узнать после входа в аккаунт vk play live в консоли разработчика, в Cookie Header'е одного из запросов. Он идёт после accessToken. Пример токена:\n7a41109f60fbb5aa16dcb4c4d3ea4a3ffac4af1d22aa8998b8a0209d0231faba (этот токен не настоящий)\nTwitch токен можно узнать на специальных сайтах. Например twitchtokengenerator.com. Пример токена:\noauth:uy1tkpc8fer0xbh122ewrmq1cked2b (этот токен не настоящий)\nTwitch токен должен иметь право chat:edit.\nДля Matrix укажите access token аккаунта (его можно найти в настройках клиента Element в разделе \"Помощь и о программе\").\nДля Twitch, Vk и Matrix достаточно ввести только токен: бот проверит его и сам узнает имя аккаунта. Если ввести \"*имя* *токен*\", бот проверит, что токен принадлежит этому аккаунту.\nДля IRC введите ник и пароль от него, если ник зарегистрирован. По умолчанию пароль используется для SASL, ?auth=nickserv или ?auth=pass в адресе канала включают NickServ IDENTIFY или серверный пароль\nСообщения, написанные с этих аккаунтов в связанных чатах, не пересылаются, поэтому лучше использовать отдельные аккаунты для бота.",
+	"help.forwarding":     "Чтобы остановить пересылку сообщений напишите /stop или /restart. /template показывает и меняет формат сообщений для всех или отдельных маршрутов. /long показывает и меняет, что делать с сообщениями длиннее ограничения площадки: обрезать (truncate) или разбить на части (split). /moderation включает для маршрутов удаление пересланных копий удалённых сообщений и повторение банов. /raw отключает для маршрутов очистку пересылаемого текста от команд площадок и невидимых символов. /status показывает состояние подключения каждого чата и сколько сообщений переслано, не доставлено и отброшено по каждому маршруту.",
+	"help.sessions":       "В одном чате может работать несколько сессий, например объединение чатов и пересылка одновременно. Команды относятся к текущей сессии. /new *имя* создаёт сессию, /switch *имя* переключает текущую, /sessions показывает список, /rename *имя* переименовывает текущую, /close *имя* останавливает и удаляет сессию. /credentials показывает токены, которые бот хранит для сессий этого чата, /revoke *номер* удаляет токен. /language *ru|en* меняет язык бота в этом чате",
+	"mode.choose":         "Выберите режим, в котором хотите использовать бота: пересылка сообщений (/forwarding) или объединение чатов (/combining)",
+	"mode.unsupported":    "Неподдерживаемый режим. Выберите /forwarding или /combining",
+	"chats.none":          "Не добавлено ни одного чата",
+	"combining.stopped":   "Чат остановлен.",
+	"combining.commands":  "Если хотите остановить чат - напишите /stop. Изменить формат сообщений - /template, подключить чат - /add, отключить - /remove, посмотреть подключённые - /list, состояние чатов - /status, ссылки на авторов - /links",
+	"language.current":    "Язык бота в этом чате: %s. Доступные языки: %s. Изменить - /language *код*",
+	"language.unknown":    "Язык %q не поддерживается. Доступные языки: %s",
+	"language.changed":    "Язык бота в этом чате: %s",
+
+	// Links to authors
+	"links.on":       "Имена авторов ведут на их каналы. Выключить - /links off",
+	"links.off":      "Ссылки на каналы авторов выключены. Включить - /links on",
+	"links.enabled":  "Имена авторов теперь ведут на их каналы.",
+	"links.disabled": "Ссылки на каналы авторов выключены.",
+	"links.usage":    "Используйте /links on или /links off",
+}
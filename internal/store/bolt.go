@@ -0,0 +1,60 @@
+package store
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const boltOpenTimeout = 5 * time.Second
+
+var boltBucket = []byte("state")
+
+// BoltStore keeps values in an embedded bolt database. Every Put is a separate durable transaction.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (bs *BoltStore) Put(key string, value []byte) error {
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Put([]byte(key), value)
+	})
+}
+
+func (bs *BoltStore) Delete(key string) error {
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).Delete([]byte(key))
+	})
+}
+
+func (bs *BoltStore) All() (map[string][]byte, error) {
+	result := make(map[string][]byte)
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucket).ForEach(func(key, value []byte) error {
+			// Slices of bolt are valid only inside the transaction.
+			result[string(key)] = append([]byte(nil), value...)
+			return nil
+		})
+	})
+	return result, err
+}
+
+func (bs *BoltStore) Close() error {
+	return bs.db.Close()
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// sealedPrefix marks version of the format of encrypted values.
+const sealedPrefix = "v1:"
+
+var (
+	ErrNoKey        = errors.New("encryption key is empty")
+	ErrCorruptValue = errors.New("encrypted value is corrupted or key is wrong")
+)
+
+// Cipher encrypts secrets saved in store with AES-GCM.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates cipher from key. Key is either 32 bytes encoded in base64 or a passphrase, which is hashed.
+func NewCipher(key string) (*Cipher, error) {
+	if key == "" {
+		return nil, ErrNoKey
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		sum := sha256.Sum256([]byte(key))
+		raw = sum[:]
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts secret. Empty secret stays empty.
+func (c *Cipher) Seal(secret string) (string, error) {
+	if secret == "" {
+		return "", nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts value made by Seal. Empty value stays empty.
+func (c *Cipher) Open(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	encoded, ok := strings.CutPrefix(value, sealedPrefix)
+	if !ok {
+		return "", ErrCorruptValue
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrCorruptValue
+	}
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	secret, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", ErrCorruptValue
+	}
+	return string(secret), nil
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCipherSealOpen(t *testing.T) {
+	rawKey := make([]byte, 32)
+	if _, err := rand.Read(rawKey); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"correct horse battery staple", base64.StdEncoding.EncodeToString(rawKey)} {
+		c, err := NewCipher(key)
+		if err != nil {
+			t.Fatalf("NewCipher(%q): %v", key, err)
+		}
+		for _, secret := range []string{"oauth:abcdef123456", "токен с пробелами", "p@ss w0rd!"} {
+			sealed, err := c.Seal(secret)
+			if err != nil {
+				t.Fatalf("Seal(%q): %v", secret, err)
+			}
+			if !strings.HasPrefix(sealed, sealedPrefix) || strings.Contains(sealed, secret) {
+				t.Errorf("Seal(%q) = %q, want encrypted value with prefix %q", secret, sealed, sealedPrefix)
+			}
+			opened, err := c.Open(sealed)
+			if err != nil || opened != secret {
+				t.Errorf("Open(Seal(%q)) = %q, %v", secret, opened, err)
+			}
+		}
+	}
+}
+
+func TestCipherSealIsRandomized(t *testing.T) {
+	c, err := NewCipher("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := c.Seal("secret")
+	second, _ := c.Seal("secret")
+	if first == second {
+		t.Errorf("Seal returned the same value twice: %q", first)
+	}
+}
+
+func TestCipherOpen(t *testing.T) {
+	c, err := NewCipher("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewCipher("other key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := other.Seal("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr error
+	}{
+		{name: "empty", value: "", want: ""},
+		{name: "not sealed", value: "oauth:plaintext", wantErr: ErrCorruptValue},
+		{name: "wrong key", value: sealed, wantErr: ErrCorruptValue},
+		{name: "not base64", value: sealedPrefix + "!!!", wantErr: ErrCorruptValue},
+		{name: "too short", value: sealedPrefix + base64.StdEncoding.EncodeToString([]byte("abc")), wantErr: ErrCorruptValue},
+		{name: "tampered", value: sealed[:len(sealed)-4] + "AAA=", wantErr: ErrCorruptValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Open(tt.value)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Open(%q) error = %v, want %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Open(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCipherSealEmpty(t *testing.T) {
+	c, err := NewCipher("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed, err := c.Seal(""); sealed != "" || err != nil {
+		t.Errorf("Seal(\"\") = %q, %v, want empty", sealed, err)
+	}
+}
+
+func TestNewCipherWithoutKey(t *testing.T) {
+	if _, err := NewCipher(""); !errors.Is(err, ErrNoKey) {
+		t.Errorf("NewCipher(\"\") error = %v, want %v", err, ErrNoKey)
+	}
+}
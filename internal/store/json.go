@@ -0,0 +1,110 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// JSONStore keeps all values in one human-readable JSON file. Values must be valid JSON themselves.
+// The file is rewritten on every change through a temporary file, so it is never left half-written.
+type JSONStore struct {
+	path string
+
+	mu     sync.Mutex
+	values map[string]json.RawMessage
+	closed bool
+}
+
+func NewJSONStore(path string) (*JSONStore, error) {
+	js := &JSONStore{path: path, values: make(map[string]json.RawMessage)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return js, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &js.values); err != nil {
+			return nil, err
+		}
+	}
+	return js, nil
+}
+
+func (js *JSONStore) Put(key string, value []byte) error {
+	if !json.Valid(value) {
+		return errors.New("value is not valid json")
+	}
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if js.closed {
+		return ErrClosed
+	}
+	js.values[key] = append(json.RawMessage(nil), value...)
+	return js.writeLocked()
+}
+
+func (js *JSONStore) Delete(key string) error {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if js.closed {
+		return ErrClosed
+	}
+	delete(js.values, key)
+	return js.writeLocked()
+}
+
+func (js *JSONStore) All() (map[string][]byte, error) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if js.closed {
+		return nil, ErrClosed
+	}
+	result := make(map[string][]byte, len(js.values))
+	for key, value := range js.values {
+		result[key] = append([]byte(nil), value...)
+	}
+	return result, nil
+}
+
+func (js *JSONStore) Close() error {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	js.closed = true
+	return nil
+}
+
+func (js *JSONStore) writeLocked() error {
+	data, err := json.MarshalIndent(js.values, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(js.path), filepath.Base(js.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), js.path)
+}
@@ -0,0 +1,28 @@
+// Package store keeps state of the bot between restarts.
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+var ErrClosed = errors.New("store is closed")
+
+// Store is a persistent map from keys to values. Implementations are safe for concurrent use.
+type Store interface {
+	Put(key string, value []byte) error
+	Delete(key string) error
+	// All returns every saved value by its key.
+	All() (map[string][]byte, error)
+	Close() error
+}
+
+// Open opens store in the file, creating it if needed. Files with .json extension are kept as one JSON object,
+// others are embedded key-value databases.
+func Open(path string) (Store, error) {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return NewJSONStore(path)
+	}
+	return NewBoltStore(path)
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStoreReopen(t *testing.T) {
+	for _, file := range []string{"state.json", "state.db"} {
+		t.Run(file, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), file)
+			s, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			// Values are JSON scalars, because the JSON store keeps objects indented.
+			for key, value := range map[string]string{"1": `"first"`, "2": `"second"`, "3": `"third"`} {
+				if err := s.Put(key, []byte(value)); err != nil {
+					t.Fatalf("Put(%q) error = %v", key, err)
+				}
+			}
+			if err := s.Put("1", []byte(`"changed"`)); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if err := s.Delete("3"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if err := s.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			s, err = Open(path)
+			if err != nil {
+				t.Fatalf("Open() after Close error = %v", err)
+			}
+			defer s.Close()
+			got, err := s.All()
+			if err != nil {
+				t.Fatalf("All() error = %v", err)
+			}
+			want := map[string][]byte{"1": []byte(`"changed"`), "2": []byte(`"second"`)}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("All() = %q, want %q", got, want)
+			}
+		})
+	}
+}
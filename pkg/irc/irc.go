@@ -0,0 +1,437 @@
+package irc
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	dialTimeout       = 15 * time.Second
+	readTimeout       = 5 * time.Minute
+	minReconnectDelay = 2 * time.Second
+	maxReconnectDelay = time.Minute
+	maxLineLength     = 510
+)
+
+var (
+	ErrNotConnected = errors.New("irc client is not connected")
+	ErrSASLFailed   = errors.New("sasl authentication failed")
+)
+
+type Config struct {
+	// Server address in host:port format.
+	Server   string
+	TLS      bool
+	Nick     string
+	User     string
+	RealName string
+	// Password sent with PASS command before registration.
+	Password string
+	// Credentials for SASL PLAIN authentication. SASL is skipped when SASLLogin is empty.
+	SASLLogin    string
+	SASLPassword string
+	// Password for NickServ IDENTIFY after registration. Skipped when empty.
+	NickServPassword string
+}
+
+type Message struct {
+	Channel string
+	Nick    string
+	Text    string
+	Time    time.Time
+	Tags    map[string]string
+}
+
+// Line is a parsed IRC protocol line.
+type Line struct {
+	Tags    map[string]string
+	Prefix  string
+	Command string
+	Params  []string
+}
+
+// Nick returns nick part of the line prefix.
+func (l Line) Nick() string {
+	nick, _, _ := strings.Cut(l.Prefix, "!")
+	return nick
+}
+
+func ParseLine(raw string) (Line, error) {
+	raw = strings.TrimRight(raw, "\r\n")
+	var line Line
+
+	if strings.HasPrefix(raw, "@") {
+		var tags string
+		tags, raw, _ = strings.Cut(raw[1:], " ")
+		line.Tags = make(map[string]string)
+		for _, tag := range strings.Split(tags, ";") {
+			key, value, _ := strings.Cut(tag, "=")
+			line.Tags[key] = unescapeTag(value)
+		}
+	}
+
+	raw = strings.TrimLeft(raw, " ")
+	if strings.HasPrefix(raw, ":") {
+		line.Prefix, raw, _ = strings.Cut(raw[1:], " ")
+	}
+
+	raw = strings.TrimLeft(raw, " ")
+	line.Command, raw, _ = strings.Cut(raw, " ")
+	if line.Command == "" {
+		return Line{}, fmt.Errorf("empty command in line %q", raw)
+	}
+	line.Command = strings.ToUpper(line.Command)
+
+	for raw != "" {
+		raw = strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(raw, ":") {
+			line.Params = append(line.Params, raw[1:])
+			break
+		}
+		var param string
+		param, raw, _ = strings.Cut(raw, " ")
+		if param != "" {
+			line.Params = append(line.Params, param)
+		}
+	}
+
+	return line, nil
+}
+
+func unescapeTag(value string) string {
+	replacer := strings.NewReplacer(`\:`, ";", `\s`, " ", `\\`, `\`, `\r`, "\r", `\n`, "\n")
+	return replacer.Replace(value)
+}
+
+type Client struct {
+	config     Config
+	msgHandler func(Message)
+	// connectHandler and disconnectHandler are called when connection is registered and when it is lost.
+	connectHandler    func()
+	disconnectHandler func(error)
+	// joinHandler is called when server confirms that the client joined a channel.
+	joinHandler func(channel string)
+	channels    []string
+
+	mu         sync.Mutex
+	conn       net.Conn
+	registered bool
+	nick       string
+	stopped    bool
+	stop       chan struct{}
+}
+
+func NewClient(config Config) *Client {
+	if config.User == "" {
+		config.User = config.Nick
+	}
+	if config.RealName == "" {
+		config.RealName = config.Nick
+	}
+	return &Client{
+		config: config,
+		stop:   make(chan struct{}),
+	}
+}
+
+// Add handler to new messages from joined channels.
+//
+// Handlers starts in one goroutine to guarantee message ordering.
+func (c *Client) OnMessage(f func(msg Message)) {
+	c.msgHandler = f
+}
+
+// OnConnect adds handler called after every successful registration on server.
+func (c *Client) OnConnect(f func()) {
+	c.connectHandler = f
+}
+
+// OnDisconnect adds handler called when connection is lost. It is not called after Disconnect.
+func (c *Client) OnDisconnect(f func(err error)) {
+	c.disconnectHandler = f
+}
+
+// OnJoin adds handler called when the client joins a channel, after every (re)connect.
+func (c *Client) OnJoin(f func(channel string)) {
+	c.joinHandler = f
+}
+
+// Join adds channel to the list of channels which are joined after every (re)connect.
+func (c *Client) Join(channel string) {
+	if !strings.HasPrefix(channel, "#") && !strings.HasPrefix(channel, "&") {
+		channel = "#" + channel
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.channels = append(c.channels, channel)
+	if c.registered {
+		c.writeLocked("JOIN " + channel)
+	}
+}
+
+// Connect connects to server and handles messages until Disconnect is called.
+//
+// Lost connections are restored with growing delay. SASL failures are returned immediately,
+// because reconnecting with the same credentials is useless.
+func (c *Client) Connect() error {
+	delay := minReconnectDelay
+	for {
+		started := time.Now()
+		err := c.connectOnce()
+		if c.isStopped() {
+			return nil
+		}
+		if c.disconnectHandler != nil {
+			c.disconnectHandler(err)
+		}
+		if errors.Is(err, ErrSASLFailed) {
+			return err
+		}
+		fmt.Printf("irc connection to %s lost: %v\n", c.config.Server, err)
+
+		if time.Since(started) > maxReconnectDelay {
+			delay = minReconnectDelay
+		}
+		select {
+		case <-c.stop:
+			return nil
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+func (c *Client) Disconnect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return nil
+	}
+	c.stopped = true
+	close(c.stop)
+	if c.conn == nil {
+		return nil
+	}
+	c.writeLocked("QUIT :bye")
+	return c.conn.Close()
+}
+
+func channelName(channel string) string {
+	if !strings.HasPrefix(channel, "#") && !strings.HasPrefix(channel, "&") {
+		return "#" + channel
+	}
+	return channel
+}
+
+// Say sends message to the channel. Line breaks are replaced by spaces, so one call is always one IRC message.
+func (c *Client) Say(channel, text string) error {
+	text = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(text)
+	return c.send(fmt.Sprintf("PRIVMSG %s :%s", channelName(channel), text))
+}
+
+// Ban sets ban on nick!*@* in the channel and kicks nick. Client must be an operator of the channel.
+func (c *Client) Ban(channel, nick string) error {
+	channel = channelName(channel)
+	return c.send(fmt.Sprintf("MODE %s +b %s!*@*", channel, nick), fmt.Sprintf("KICK %s %s", channel, nick))
+}
+
+// Unban removes ban set by Ban.
+func (c *Client) Unban(channel, nick string) error {
+	return c.send(fmt.Sprintf("MODE %s -b %s!*@*", channelName(channel), nick))
+}
+
+func (c *Client) send(lines ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.registered {
+		return ErrNotConnected
+	}
+	for _, line := range lines {
+		if err := c.writeLocked(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) isStopped() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopped
+}
+
+func (c *Client) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if c.config.TLS {
+		host, _, _ := net.SplitHostPort(c.config.Server)
+		return tls.DialWithDialer(dialer, "tcp", c.config.Server, &tls.Config{ServerName: host})
+	}
+	return dialer.Dial("tcp", c.config.Server)
+}
+
+func (c *Client) connectOnce() error {
+	conn, err := c.dial()
+	if err != nil {
+		return fmt.Errorf("dial error: %w", err)
+	}
+
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		conn.Close()
+		return nil
+	}
+	c.conn = conn
+	c.nick = c.config.Nick
+	c.registered = false
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.registered = false
+		c.conn = nil
+		c.mu.Unlock()
+		conn.Close()
+	}()
+
+	if err := c.register(); err != nil {
+		return fmt.Errorf("register error: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		raw, err := reader.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("read error: %w", err)
+		}
+
+		line, err := ParseLine(raw)
+		if err != nil {
+			continue
+		}
+		if err := c.handleLine(line); err != nil {
+			return err
+		}
+		if line.Command == "001" && c.connectHandler != nil {
+			c.connectHandler()
+		}
+	}
+}
+
+func (c *Client) register() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.config.SASLLogin != "" {
+		if err := c.writeLocked("CAP REQ :sasl"); err != nil {
+			return err
+		}
+	}
+	if c.config.Password != "" {
+		if err := c.writeLocked("PASS " + c.config.Password); err != nil {
+			return err
+		}
+	}
+	if err := c.writeLocked("NICK " + c.nick); err != nil {
+		return err
+	}
+	return c.writeLocked(fmt.Sprintf("USER %s 0 * :%s", c.config.User, c.config.RealName))
+}
+
+func (c *Client) handleLine(line Line) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch line.Command {
+	case "PING":
+		return c.writeLocked("PONG :" + strings.Join(line.Params, " "))
+	case "CAP":
+		if len(line.Params) >= 3 && line.Params[1] == "ACK" && strings.Contains(line.Params[2], "sasl") {
+			return c.writeLocked("AUTHENTICATE PLAIN")
+		}
+		if len(line.Params) >= 2 && line.Params[1] == "NAK" {
+			return ErrSASLFailed
+		}
+	case "AUTHENTICATE":
+		if len(line.Params) > 0 && line.Params[0] == "+" {
+			payload := c.config.SASLLogin + "\x00" + c.config.SASLLogin + "\x00" + c.config.SASLPassword
+			return c.writeLocked("AUTHENTICATE " + base64.StdEncoding.EncodeToString([]byte(payload)))
+		}
+	case "903": // RPL_SASLSUCCESS
+		return c.writeLocked("CAP END")
+	case "902", "904", "905", "906": // SASL errors
+		return ErrSASLFailed
+	case "433": // ERR_NICKNAMEINUSE
+		if c.registered {
+			return nil
+		}
+		c.nick += "_"
+		return c.writeLocked("NICK " + c.nick)
+	case "001": // RPL_WELCOME
+		c.registered = true
+		if len(line.Params) > 0 {
+			c.nick = line.Params[0]
+		}
+		if c.config.NickServPassword != "" {
+			if err := c.writeLocked("PRIVMSG NickServ :IDENTIFY " + c.config.NickServPassword); err != nil {
+				return err
+			}
+		}
+		for _, channel := range c.channels {
+			if err := c.writeLocked("JOIN " + channel); err != nil {
+				return err
+			}
+		}
+	case "JOIN":
+		if len(line.Params) == 0 || !strings.EqualFold(line.Nick(), c.nick) || c.joinHandler == nil {
+			return nil
+		}
+		c.mu.Unlock()
+		c.joinHandler(line.Params[0])
+		c.mu.Lock()
+	case "PRIVMSG":
+		if len(line.Params) < 2 || c.msgHandler == nil {
+			return nil
+		}
+		msg := Message{Channel: line.Params[0], Nick: line.Nick(), Text: line.Params[1], Time: time.Now(), Tags: line.Tags}
+		if t, err := time.Parse(time.RFC3339Nano, line.Tags["time"]); err == nil {
+			msg.Time = t
+		}
+		if !strings.HasPrefix(msg.Channel, "#") && !strings.HasPrefix(msg.Channel, "&") {
+			return nil
+		}
+
+		// Handler may call Say, so it must be called without lock.
+		c.mu.Unlock()
+		c.msgHandler(msg)
+		c.mu.Lock()
+	}
+	return nil
+}
+
+func (c *Client) writeLocked(line string) error {
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+	if len(line) > maxLineLength {
+		cut := maxLineLength
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		line = line[:cut]
+	}
+	_, err := c.conn.Write([]byte(line + "\r\n"))
+	return err
+}
@@ -0,0 +1,326 @@
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	syncTimeout       = 30 * time.Second
+	httpTimeout       = syncTimeout + 30*time.Second
+	minRetryDelay     = 2 * time.Second
+	maxRetryDelay     = time.Minute
+	messageEventType  = "m.room.message"
+	clientAPIPrefix   = "/_matrix/client/v3"
+	unknownTokenError = "M_UNKNOWN_TOKEN"
+)
+
+var ErrUnauthorized = errors.New("matrix access token is invalid")
+
+type Message struct {
+	ID     string
+	Sender string
+	Body   string
+	Time   time.Time
+	// ReplyTo is id of the event this message replies to.
+	ReplyTo string
+}
+
+type apiError struct {
+	ErrCode string `json:"errcode"`
+	Error   string `json:"error"`
+}
+
+type event struct {
+	Type           string `json:"type"`
+	EventID        string `json:"event_id"`
+	Sender         string `json:"sender"`
+	OriginServerTS int64  `json:"origin_server_ts"`
+	Content        struct {
+		MsgType   string `json:"msgtype"`
+		Body      string `json:"body"`
+		RelatesTo struct {
+			InReplyTo struct {
+				EventID string `json:"event_id"`
+			} `json:"m.in_reply_to"`
+		} `json:"m.relates_to"`
+	} `json:"content"`
+}
+
+type syncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []event `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+	} `json:"rooms"`
+}
+
+type Client struct {
+	homeserver  string
+	accessToken string
+	roomID      string
+	msgHandler  func(Message)
+	// syncHandler is called after every sync with its error, nil if sync succeeded.
+	syncHandler func(error)
+	client      *http.Client
+	ctx         context.Context
+	cancel      context.CancelFunc
+	txnCounter  int64
+}
+
+// NewClient creates client for homeserver (for example https://matrix.org) authorized by access token.
+func NewClient(homeserver, accessToken string) *Client {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Client{
+		homeserver:  strings.TrimRight(homeserver, "/"),
+		accessToken: accessToken,
+		client:      &http.Client{Timeout: httpTimeout},
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// Add handler to new messages from the room.
+//
+// Handlers starts in one goroutine to guarantee message ordering.
+func (c *Client) OnMessage(f func(msg Message)) {
+	c.msgHandler = f
+}
+
+// OnSync adds handler called after every sync request with its error, nil if sync succeeded.
+func (c *Client) OnSync(f func(err error)) {
+	c.syncHandler = f
+}
+
+// WhoAmI returns user id of the access token owner.
+func (c *Client) WhoAmI() (string, error) {
+	var resp struct {
+		UserID string `json:"user_id"`
+	}
+	if err := c.do(http.MethodGet, "/account/whoami", nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.UserID, nil
+}
+
+// Join joins room by its id (!id:server) or alias (#alias:server). The account must be allowed to join it.
+func (c *Client) Join(room string) error {
+	var resp struct {
+		RoomID string `json:"room_id"`
+	}
+	path := "/join/" + url.PathEscape(room)
+	if err := c.do(http.MethodPost, path, struct{}{}, &resp); err != nil {
+		return fmt.Errorf("unable to join room %s: %w", room, err)
+	}
+	c.roomID = resp.RoomID
+	return nil
+}
+
+// Connect reads room timeline with long-poll /sync until Disconnect is called.
+//
+// Messages sent before connecting are skipped. Network errors are retried with growing delay.
+func (c *Client) Connect() error {
+	if c.roomID == "" {
+		return errors.New("room is not joined")
+	}
+
+	filter, err := json.Marshal(map[string]interface{}{
+		"account_data": map[string]interface{}{"types": []string{}},
+		"presence":     map[string]interface{}{"types": []string{}},
+		"room": map[string]interface{}{
+			"rooms":        []string{c.roomID},
+			"timeline":     map[string]interface{}{"types": []string{messageEventType}},
+			"state":        map[string]interface{}{"types": []string{}},
+			"ephemeral":    map[string]interface{}{"types": []string{}},
+			"account_data": map[string]interface{}{"types": []string{}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	since := ""
+	delay := minRetryDelay
+	for {
+		query := url.Values{}
+		query.Set("filter", string(filter))
+		if since != "" {
+			query.Set("since", since)
+			query.Set("timeout", fmt.Sprint(syncTimeout.Milliseconds()))
+		}
+
+		var resp syncResponse
+		err := c.do(http.MethodGet, "/sync?"+query.Encode(), nil, &resp)
+		if c.ctx.Err() != nil {
+			return nil
+		}
+		if c.syncHandler != nil {
+			c.syncHandler(err)
+		}
+		if errors.Is(err, ErrUnauthorized) {
+			return err
+		}
+		if err != nil {
+			fmt.Printf("matrix sync error: %v\n", err)
+			select {
+			case <-c.ctx.Done():
+				return nil
+			case <-time.After(delay):
+			}
+			delay *= 2
+			if delay > maxRetryDelay {
+				delay = maxRetryDelay
+			}
+			continue
+		}
+		delay = minRetryDelay
+
+		if since != "" {
+			c.handleEvents(resp.Rooms.Join[c.roomID].Timeline.Events)
+		}
+		since = resp.NextBatch
+	}
+}
+
+func (c *Client) handleEvents(events []event) {
+	if c.msgHandler == nil {
+		return
+	}
+	for _, ev := range events {
+		if ev.Type != messageEventType || ev.Content.Body == "" {
+			continue
+		}
+		msg := Message{
+			ID:      ev.EventID,
+			Sender:  ev.Sender,
+			Body:    ev.Content.Body,
+			Time:    time.UnixMilli(ev.OriginServerTS),
+			ReplyTo: ev.Content.RelatesTo.InReplyTo.EventID,
+		}
+		if msg.ReplyTo != "" {
+			msg.Body = stripReplyFallback(msg.Body)
+		}
+		c.msgHandler(msg)
+	}
+}
+
+func (c *Client) Disconnect() error {
+	c.cancel()
+	return nil
+}
+
+// stripReplyFallback removes quote of the parent message which clients put before text of reply.
+func stripReplyFallback(body string) string {
+	lines := strings.Split(body, "\n")
+	i := 0
+	for i < len(lines) && strings.HasPrefix(lines[i], ">") {
+		i++
+	}
+	if i == 0 {
+		return body
+	}
+	return strings.TrimLeft(strings.Join(lines[i:], "\n"), "\n")
+}
+
+// SendMessage sends plain text message to the joined room and returns id of the created event.
+func (c *Client) SendMessage(text string) (string, error) {
+	return c.SendReply(text, "")
+}
+
+// SendReply sends plain text message as reply to the event. Message is not a reply if replyTo is empty.
+func (c *Client) SendReply(text, replyTo string) (string, error) {
+	if c.roomID == "" {
+		return "", errors.New("room is not joined")
+	}
+
+	path := fmt.Sprintf("/rooms/%s/send/%s/%s", url.PathEscape(c.roomID), messageEventType, c.nextTxnID())
+	body := map[string]interface{}{"msgtype": "m.text", "body": text}
+	if replyTo != "" {
+		body["m.relates_to"] = map[string]interface{}{
+			"m.in_reply_to": map[string]string{"event_id": replyTo},
+		}
+	}
+
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	if err := c.do(http.MethodPut, path, body, &resp); err != nil {
+		return "", err
+	}
+	return resp.EventID, nil
+}
+
+// Redact removes content of the event in the joined room.
+func (c *Client) Redact(eventID, reason string) error {
+	if c.roomID == "" {
+		return errors.New("room is not joined")
+	}
+
+	path := fmt.Sprintf("/rooms/%s/redact/%s/%s", url.PathEscape(c.roomID), url.PathEscape(eventID), c.nextTxnID())
+	body := map[string]string{}
+	if reason != "" {
+		body["reason"] = reason
+	}
+	return c.do(http.MethodPut, path, body, nil)
+}
+
+func (c *Client) nextTxnID() string {
+	return fmt.Sprintf("combchats-%d-%d", time.Now().UnixNano(), atomic.AddInt64(&c.txnCounter, 1))
+}
+
+func (c *Client) do(method, path string, reqBody, respBody interface{}) error {
+	var body io.Reader
+	if reqBody != nil {
+		data, err := json.Marshal(reqBody)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, method, c.homeserver+clientAPIPrefix+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr apiError
+		_ = json.Unmarshal(data, &apiErr)
+		if resp.StatusCode == http.StatusUnauthorized || apiErr.ErrCode == unknownTokenError {
+			return ErrUnauthorized
+		}
+		return fmt.Errorf("failed to get response: %s %s %s", resp.Status, apiErr.ErrCode, apiErr.Error)
+	}
+
+	if respBody == nil {
+		return nil
+	}
+	return json.Unmarshal(data, respBody)
+}
@@ -0,0 +1,213 @@
+// Package twitchapi is a minimal client of Twitch Helix API used for moderation on behalf of a chat account.
+package twitchapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	helixURL    = "https://api.twitch.tv/helix"
+	validateURL = "https://id.twitch.tv/oauth2/validate"
+
+	// maxBanDuration is the longest timeout allowed by twitch, longer bans are permanent.
+	maxBanDuration = 1209600 * time.Second
+)
+
+var (
+	ErrUnauthorized = errors.New("twitch token is invalid or expired")
+	ErrUserNotFound = errors.New("twitch user not found")
+)
+
+// Token describes OAuth token of an account.
+type Token struct {
+	ClientID string   `json:"client_id"`
+	Login    string   `json:"login"`
+	UserID   string   `json:"user_id"`
+	Scopes   []string `json:"scopes"`
+	// ExpiresIn is number of seconds left until token expires.
+	ExpiresIn int `json:"expires_in"`
+}
+
+// HasScope reports whether token is granted the scope.
+func (t Token) HasScope(scope string) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type apiError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+type Client struct {
+	accessToken string
+	client      *http.Client
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// NewClient creates client for access token, "oauth:" prefix of chat tokens is allowed.
+func NewClient(accessToken string) *Client {
+	return &Client{
+		accessToken: strings.TrimPrefix(accessToken, "oauth:"),
+		client: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+	}
+}
+
+// Validate checks token and returns its description.
+func (c *Client) Validate() (Token, error) {
+	req, err := http.NewRequest(http.MethodGet, validateURL, nil)
+	if err != nil {
+		return Token{}, err
+	}
+	req.Header.Set("Authorization", "OAuth "+c.accessToken)
+
+	var token Token
+	if err := c.send(req, &token); err != nil {
+		return Token{}, err
+	}
+
+	c.mu.Lock()
+	c.token = &token
+	c.mu.Unlock()
+	return token, nil
+}
+
+// validated returns description of token, validating it on first call. Helix requests need client id of token.
+func (c *Client) validated() (Token, error) {
+	c.mu.Lock()
+	token := c.token
+	c.mu.Unlock()
+	if token != nil {
+		return *token, nil
+	}
+	return c.Validate()
+}
+
+// UserID returns id of user by login.
+func (c *Client) UserID(login string) (string, error) {
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	query := url.Values{"login": {strings.ToLower(login)}}
+	if err := c.do(http.MethodGet, "/users?"+query.Encode(), nil, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Data) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrUserNotFound, login)
+	}
+	return resp.Data[0].ID, nil
+}
+
+// DeleteChatMessage deletes message in the chat of broadcaster on behalf of the token owner.
+// Token must have moderator:manage:chat_messages scope.
+func (c *Client) DeleteChatMessage(broadcasterID, messageID string) error {
+	token, err := c.validated()
+	if err != nil {
+		return err
+	}
+	query := url.Values{
+		"broadcaster_id": {broadcasterID},
+		"moderator_id":   {token.UserID},
+		"message_id":     {messageID},
+	}
+	return c.do(http.MethodDelete, "/moderation/chat?"+query.Encode(), nil, nil)
+}
+
+// BanUser bans user in the chat of broadcaster on behalf of the token owner. Zero duration means permanent ban.
+// Token must have moderator:manage:banned_users scope.
+func (c *Client) BanUser(broadcasterID, userID string, duration time.Duration, reason string) error {
+	token, err := c.validated()
+	if err != nil {
+		return err
+	}
+	query := url.Values{
+		"broadcaster_id": {broadcasterID},
+		"moderator_id":   {token.UserID},
+	}
+
+	data := map[string]interface{}{"user_id": userID}
+	if duration > 0 && duration <= maxBanDuration {
+		seconds := int(duration / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		data["duration"] = seconds
+	}
+	if reason != "" {
+		data["reason"] = reason
+	}
+	return c.do(http.MethodPost, "/moderation/bans?"+query.Encode(), map[string]interface{}{"data": data}, nil)
+}
+
+func (c *Client) do(method, path string, reqBody, respBody interface{}) error {
+	token, err := c.validated()
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	if reqBody != nil {
+		data, err := json.Marshal(reqBody)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, helixURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	req.Header.Set("Client-Id", token.ClientID)
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, respBody)
+}
+
+func (c *Client) send(req *http.Request, respBody interface{}) error {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if resp.StatusCode == http.StatusUnauthorized {
+			return ErrUnauthorized
+		}
+		var apiErr apiError
+		_ = json.Unmarshal(data, &apiErr)
+		return fmt.Errorf("failed to get response: %s %s", resp.Status, apiErr.Message)
+	}
+
+	if respBody == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, respBody)
+}
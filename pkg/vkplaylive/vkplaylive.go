@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,9 +18,22 @@ import (
 const (
 	wsConnectionAddr = "wss://pubsub.live.vkplay.ru/connection/websocket"
 	wsTokenURL       = "https://api.live.vkplay.ru/v1/ws/connect"
+	currentUserURL   = "https://api.live.vkplay.ru/v1/user/current"
 	originURL        = "https://live.vkplay.ru"
 )
 
+var (
+	ErrUnauthorized    = errors.New("vk play live token is invalid or expired")
+	ErrUnknownResponse = errors.New("vk play live response format is not recognized")
+	ErrDisconnected    = errors.New("vk play live client is disconnected")
+)
+
+// User is the account of the token.
+type User struct {
+	ID   int
+	Name string
+}
+
 type wsMessage struct {
 	ID     int         `json:"id"`
 	Method int         `json:"method,omitempty"`
@@ -49,9 +63,17 @@ type MessageSubject struct {
 }
 
 type Message struct {
+	ID     int
 	Data   []MessageSubject
 	Author string
 	Time   int64
+	// Parent is the message this message replies to, nil if it is not a reply.
+	Parent *ParentMessage
+}
+
+type ParentMessage struct {
+	ID     int
+	Author string
 }
 
 type rawMessageData struct {
@@ -59,9 +81,16 @@ type rawMessageData struct {
 		Data struct {
 			Data struct {
 				Data struct {
+					ID     int `json:"id"`
 					Author struct {
 						Name string `json:"displayName"`
 					} `json:"author"`
+					Parent *struct {
+						ID     int `json:"id"`
+						Author struct {
+							Name string `json:"displayName"`
+						} `json:"author"`
+					} `json:"parent"`
 					CreatedAt int64 `json:"createdAt"`
 					Data      []struct {
 						Type    string `json:"type"`
@@ -170,19 +199,29 @@ func createMessage(rawMsg rawMessageData) (Message, error) {
 		}
 	}
 
-	return Message{
+	msg := Message{
+		ID:     rawMsg.Result.Data.Data.Data.ID,
 		Author: rawMsg.Result.Data.Data.Data.Author.Name,
 		Time:   rawMsg.Result.Data.Data.Data.CreatedAt,
 		Data:   subjs,
-	}, nil
+	}
+	if parent := rawMsg.Result.Data.Data.Data.Parent; parent != nil {
+		msg.Parent = &ParentMessage{ID: parent.ID, Author: parent.Author.Name}
+	}
+	return msg, nil
 }
 
 type Client struct {
 	msgHandler func(Message)
+	onConnect  func()
 	channel    string
 	authToken  string
 	client     *http.Client
-	ws         *websocket.Conn
+
+	// mu guards ws and disconnected, Disconnect may be called before Connect dials.
+	mu           sync.Mutex
+	ws           *websocket.Conn
+	disconnected bool
 }
 
 func NewAnonymousClient() *Client {
@@ -205,14 +244,19 @@ func (c *Client) OnMessage(f func(msg Message)) {
 	c.msgHandler = f
 }
 
+// Add handler called once the client is subscribed to the chat and starts receiving messages.
+func (c *Client) OnConnect(f func()) {
+	c.onConnect = f
+}
+
 func (c *Client) Join(channel string) {
 	c.channel = channel
 }
 
-func (c *Client) handleMessages() error {
+func (c *Client) handleMessages(ws *websocket.Conn) error {
 	for {
 		var rawMessage rawMessageData
-		if err := c.ws.ReadJSON(&rawMessage); err != nil {
+		if err := ws.ReadJSON(&rawMessage); err != nil {
 			return fmt.Errorf("read error: %w", err) // TODO: do continue, not return
 		}
 
@@ -229,19 +273,26 @@ func (c *Client) Connect() error {
 	headers := http.Header{}
 	headers.Add("Origin", originURL)
 
-	var err error
-	c.ws, _, err = websocket.DefaultDialer.Dial(wsConnectionAddr, headers)
+	ws, _, err := websocket.DefaultDialer.Dial(wsConnectionAddr, headers)
 	if err != nil {
 		return fmt.Errorf("dial error: %w", err)
 	}
-	defer c.ws.Close()
+	defer ws.Close()
+
+	c.mu.Lock()
+	if c.disconnected {
+		c.mu.Unlock()
+		return ErrDisconnected
+	}
+	c.ws = ws
+	c.mu.Unlock()
 
 	token, err := getWebSocketToken()
 	if err != nil {
 		return fmt.Errorf("unable to get web socket token: %w", err)
 	}
 
-	err = initWebSocket(c.ws, token)
+	err = initWebSocket(ws, token)
 	if err != nil {
 		return fmt.Errorf("unable to initialize websocket: %w", err)
 	}
@@ -260,14 +311,24 @@ func (c *Client) Connect() error {
 		},
 		Method: 1,
 	}
-	if err := invokeMethod(c.ws, &connectToChatPayload); err != nil {
+	if err := invokeMethod(ws, &connectToChatPayload); err != nil {
 		return fmt.Errorf("connect to chat error: %w", err)
 	}
+	if c.onConnect != nil {
+		c.onConnect()
+	}
 
-	return c.handleMessages()
+	return c.handleMessages(ws)
 }
 
+// Disconnect closes the connection. Connect which is not connected yet returns ErrDisconnected.
 func (c *Client) Disconnect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disconnected = true
+	if c.ws == nil {
+		return nil
+	}
 	return c.ws.Close()
 }
 
@@ -279,32 +340,110 @@ type messageBlock struct {
 	Explicit    bool   `json:"explicit,omitempty"`
 }
 
-func (c *Client) SendMessage(message string) error {
+// SendMessage sends message to the joined channel and returns id of the created message.
+func (c *Client) SendMessage(message string) (int, error) {
 	serializedMessage := c.serializeMessage(message)
 	serializedMessageJSON, err := json.Marshal(serializedMessage)
 	if err != nil {
-		return fmt.Errorf("error marshaling message: %v", err)
+		return 0, fmt.Errorf("error marshaling message: %v", err)
 	}
 
 	body := url.Values{}
 	body.Add("data", string(serializedMessageJSON))
 
-	url := fmt.Sprintf("https://api.live.vkplay.ru/v1/blog/%s/public_video_stream/chat", c.channel)
-	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body.Encode()))
+	var created struct {
+		ID int `json:"id"`
+	}
+	if err := c.chatRequest(http.MethodPost, "", strings.NewReader(body.Encode()), &created); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+// DeleteMessage deletes message from the joined channel. Account must be the author or a moderator of the channel.
+func (c *Client) DeleteMessage(id int) error {
+	return c.chatRequest(http.MethodDelete, fmt.Sprintf("/%d", id), nil, nil)
+}
+
+// CurrentUser returns account of the token. It checks token without sending anything.
+// ErrUnauthorized is returned only if the API rejects the token, ErrUnknownResponse if the answer can't be read.
+func (c *Client) CurrentUser() (User, error) {
+	req, err := http.NewRequest(http.MethodGet, currentUserURL, nil)
+	if err != nil {
+		return User{}, err
+	}
+	req.Header.Add("Authorization", "Bearer "+c.authToken)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return User{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return User{}, ErrUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		return User{}, fmt.Errorf("failed to get response: %s", resp.Status)
+	}
+
+	// Response format is not documented, the user is looked for at the top level and in data.
+	type rawUser struct {
+		ID          int    `json:"id"`
+		DisplayName string `json:"displayName"`
+		Nick        string `json:"nick"`
+	}
+	var raw struct {
+		rawUser
+		Data *rawUser `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return User{}, fmt.Errorf("%w: %v", ErrUnknownResponse, err)
+	}
+	user := raw.rawUser
+	if raw.Data != nil {
+		user = *raw.Data
+	}
+	if user.DisplayName == "" {
+		user.DisplayName = user.Nick
+	}
+	if user.DisplayName == "" {
+		return User{}, fmt.Errorf("%w: no user name", ErrUnknownResponse)
+	}
+	return User{ID: user.ID, Name: user.DisplayName}, nil
+}
+
+func (c *Client) chatRequest(method, path string, body io.Reader, respBody interface{}) error {
+	url := fmt.Sprintf("https://api.live.vkplay.ru/v1/blog/%s/public_video_stream/chat%s", c.channel, path)
+	req, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return err
 	}
 
 	req.Header.Add("Authorization", "Bearer "+c.authToken)
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	if body != nil {
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
-	fmt.Printf("Got response from vk: %v\n", resp.Header)
 
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get response: %s", resp.Status)
+	}
+	if respBody == nil {
+		return nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	// Response format is not documented, message is considered sent even if id is not found.
+	_ = json.Unmarshal(data, respBody)
 	return nil
 }
 